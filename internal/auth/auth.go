@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"fmt"
+	"goload/internal/client"
+	"goload/internal/metrics"
+	"goload/types"
+	"net/http"
+	"sync"
+)
+
+// Provider authenticates outgoing requests.
+type Provider interface {
+	Apply(req *http.Request) error
+}
+
+type basicProvider struct {
+	username string
+	password string
+}
+
+func (p *basicProvider) Apply(req *http.Request) error {
+	req.SetBasicAuth(p.username, p.password)
+	return nil
+}
+
+type bearerProvider struct {
+	token string
+}
+
+func (p *bearerProvider) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	return nil
+}
+
+func NewProvider(config *types.AuthConfig, collector *metrics.MetricsCollector) (Provider, error) {
+	switch config.Type {
+	case types.BasicAuth:
+		return &basicProvider{username: config.Username, password: config.Password}, nil
+	case types.BearerAuth:
+		if config.Token == "" {
+			return nil, fmt.Errorf("bearer auth requires a token")
+		}
+		return &bearerProvider{token: config.Token}, nil
+	case types.OAuth2ClientCredentials, types.OAuth2Password:
+		if config.TokenURL == "" {
+			return nil, fmt.Errorf("%s auth requires a token_url", config.Type)
+		}
+		return newTokenProvider(config, collector, oauth2Fetcher(config)), nil
+	case types.SetupTokenAuth:
+		if config.Setup == nil {
+			return nil, fmt.Errorf("setup auth requires a setup request")
+		}
+		return newTokenProvider(config, collector, setupFetcher(config)), nil
+	default:
+		return nil, fmt.Errorf("unknown auth type: %s", config.Type)
+	}
+}
+
+// Registry creates providers lazily and shares them between all the VUs of a
+// test, so that a token is fetched once and not once per VU.
+type Registry struct {
+	collector *metrics.MetricsCollector
+	providers map[*types.AuthConfig]Provider
+	mu        sync.Mutex
+}
+
+func NewRegistry(collector *metrics.MetricsCollector) *Registry {
+	return &Registry{
+		collector: collector,
+		providers: make(map[*types.AuthConfig]Provider),
+	}
+}
+
+// Signer adapts the provider of config to a client.Signer, so that requests
+// are authenticated by client.CreateRequest before any signature is computed.
+func (r *Registry) Signer(config *types.AuthConfig) client.Signer {
+	return &authSigner{registry: r, config: config}
+}
+
+type authSigner struct {
+	registry *Registry
+	config   *types.AuthConfig
+}
+
+func (s *authSigner) Sign(req *http.Request, body []byte) error {
+	return s.registry.Apply(s.config, req)
+}
+
+func (r *Registry) Apply(config *types.AuthConfig, req *http.Request) error {
+	if config == nil {
+		return nil
+	}
+	r.mu.Lock()
+	provider, ok := r.providers[config]
+	if !ok {
+		var err error
+		provider, err = NewProvider(config, r.collector)
+		if err != nil {
+			r.mu.Unlock()
+			return err
+		}
+		r.providers[config] = provider
+	}
+	r.mu.Unlock()
+	return provider.Apply(req)
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"goload/internal/client"
+	"goload/internal/metrics"
+	"goload/types"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRefreshBefore = 30 * time.Second
+
+// refreshRetryDelay spaces out the refreshes of a valid token after a failed
+// one, so a failing token endpoint is not called on every request.
+const refreshRetryDelay = 5 * time.Second
+
+var tokenHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+type token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// fetchFunc obtains a new token. previous is the token being replaced, nil on
+// the first fetch.
+type fetchFunc func(previous *token) (*token, error)
+
+// tokenProvider caches a token and fetches a new one shortly before it
+// expires. A single fetch runs at a time, without blocking the VUs while the
+// current token is still valid. Fetches are timed and reported to the
+// collector as auth metrics, apart from the test traffic.
+type tokenProvider struct {
+	config    *types.AuthConfig
+	collector *metrics.MetricsCollector
+	fetch     fetchFunc
+	current   *token
+	refreshAt time.Time
+	expiresAt time.Time
+	fetching  chan struct{} // Closed when the fetch in flight is over
+	fetchErr  error         // Error of the last fetch
+	mu        sync.Mutex
+}
+
+func newTokenProvider(config *types.AuthConfig, collector *metrics.MetricsCollector, fetch fetchFunc) *tokenProvider {
+	return &tokenProvider{
+		config:    config,
+		collector: collector,
+		fetch:     fetch,
+	}
+}
+
+func (p *tokenProvider) Apply(req *http.Request) error {
+	accessToken, err := p.token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+func (p *tokenProvider) token() (string, error) {
+	p.mu.Lock()
+	if p.current != nil && (p.refreshAt.IsZero() || time.Now().Before(p.refreshAt)) {
+		defer p.mu.Unlock()
+		return p.current.AccessToken, nil
+	}
+	if fetching := p.fetching; fetching != nil {
+		// Another VU is fetching, wait for it only without a token to send
+		if p.valid() {
+			defer p.mu.Unlock()
+			return p.current.AccessToken, nil
+		}
+		p.mu.Unlock()
+		<-fetching
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.fetchErr != nil && !p.valid() {
+			return "", p.fetchErr
+		}
+		return p.current.AccessToken, nil
+	}
+	fetching := make(chan struct{})
+	p.fetching = fetching
+	previous := p.current
+	p.mu.Unlock()
+
+	startTime := time.Now()
+	fetched, err := p.fetch(previous)
+	if err != nil && previous != nil && previous.RefreshToken != "" {
+		// The refresh token may have been revoked, start over with the grant
+		fetched, err = p.fetch(nil)
+	}
+	if p.collector != nil {
+		_ = p.collector.IngestAuthMetric(types.RequestMetric{
+			Duration: time.Since(startTime),
+			Failed:   err != nil,
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer close(fetching)
+	p.fetching = nil
+	p.fetchErr = nil
+	if err != nil {
+		p.fetchErr = fmt.Errorf("error fetching %s token: %s", p.config.Type, err)
+		if p.valid() {
+			// Retry the refresh later, before the token expires
+			p.refreshAt = time.Now().Add(refreshRetryDelay)
+			if !p.expiresAt.IsZero() && p.expiresAt.Before(p.refreshAt) {
+				p.refreshAt = p.expiresAt
+			}
+			return p.current.AccessToken, nil
+		}
+		return "", p.fetchErr
+	}
+
+	lifetime := fetched.ExpiresIn
+	if lifetime == 0 {
+		lifetime = p.config.TokenTTL
+	}
+	p.refreshAt = time.Time{}
+	p.expiresAt = time.Time{}
+	if lifetime > 0 {
+		refreshBefore := p.config.RefreshBefore
+		if refreshBefore == 0 {
+			refreshBefore = defaultRefreshBefore
+		}
+		if refreshBefore >= lifetime {
+			refreshBefore = lifetime / 2
+		}
+		p.refreshAt = startTime.Add(lifetime - refreshBefore)
+		p.expiresAt = startTime.Add(lifetime)
+	}
+	p.current = fetched
+	return fetched.AccessToken, nil
+}
+
+// valid reports whether the current token has not expired yet. It must be
+// called with the lock held.
+func (p *tokenProvider) valid() bool {
+	return p.current != nil && (p.expiresAt.IsZero() || time.Now().Before(p.expiresAt))
+}
+
+func oauth2Fetcher(config *types.AuthConfig) fetchFunc {
+	return func(previous *token) (*token, error) {
+		form := url.Values{}
+		switch {
+		case previous != nil && previous.RefreshToken != "":
+			form.Set("grant_type", "refresh_token")
+			form.Set("refresh_token", previous.RefreshToken)
+		case config.Type == types.OAuth2Password:
+			form.Set("grant_type", "password")
+			form.Set("username", config.Username)
+			form.Set("password", config.Password)
+		default:
+			form.Set("grant_type", "client_credentials")
+		}
+		if len(config.Scopes) > 0 {
+			form.Set("scope", strings.Join(config.Scopes, " "))
+		}
+		if config.ClientAuth == "body" {
+			form.Set("client_id", config.ClientID)
+			form.Set("client_secret", config.ClientSecret)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, config.TokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		if config.ClientAuth != "body" && config.ClientID != "" {
+			req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+		}
+
+		body, err := doTokenRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			AccessToken  string      `json:"access_token"`
+			RefreshToken string      `json:"refresh_token"`
+			ExpiresIn    json.Number `json:"expires_in"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("invalid token response: %s", err)
+		}
+		if response.AccessToken == "" {
+			return nil, fmt.Errorf("token response has no access_token")
+		}
+		expiresIn, _ := response.ExpiresIn.Float64()
+		return &token{
+			AccessToken:  response.AccessToken,
+			RefreshToken: response.RefreshToken,
+			ExpiresIn:    time.Duration(expiresIn * float64(time.Second)),
+		}, nil
+	}
+}
+
+// setupFetcher sends the setup request and extracts the token found at
+// TokenPath ("access_token" by default) of its JSON response. An "expires_in"
+// field next to the token is honoured.
+func setupFetcher(config *types.AuthConfig) fetchFunc {
+	return func(previous *token) (*token, error) {
+		req, err := client.CreateRequest(*config.Setup)
+		if err != nil {
+			return nil, err
+		}
+		body, err := doTokenRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("invalid setup response: %s", err)
+		}
+
+		path := config.TokenPath
+		if path == "" {
+			path = "access_token"
+		}
+		value, ok := lookup(document, path)
+		accessToken, isString := value.(string)
+		if !ok || !isString || accessToken == "" {
+			return nil, fmt.Errorf("no token found at %s in setup response", path)
+		}
+
+		fetched := &token{AccessToken: accessToken}
+		parent := path[:max(strings.LastIndex(path, "."), 0)]
+		expiresPath := "expires_in"
+		if parent != "" {
+			expiresPath = parent + ".expires_in"
+		}
+		if expiresIn, ok := lookup(document, expiresPath); ok {
+			if seconds, err := strconv.ParseFloat(fmt.Sprint(expiresIn), 64); err == nil {
+				fetched.ExpiresIn = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		return fetched, nil
+	}
+}
+
+func doTokenRequest(req *http.Request) ([]byte, error) {
+	resp, err := tokenHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// lookup resolves a dotted path such as "data.tokens.0.value" in a decoded
+// JSON document.
+func lookup(document interface{}, path string) (interface{}, bool) {
+	current := document
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"errors"
+	"goload/types"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenIsFetchedOnceByConcurrentCallers(t *testing.T) {
+	var fetches atomic.Int32
+	provider := newTokenProvider(&types.AuthConfig{Type: types.OAuth2ClientCredentials}, nil, func(previous *token) (*token, error) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return &token{AccessToken: "first", ExpiresIn: time.Hour}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if accessToken, err := provider.token(); err != nil || accessToken != "first" {
+				t.Errorf("got %q, %v, want first", accessToken, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches.Load() != 1 {
+		t.Errorf("token fetched %d times, want 1", fetches.Load())
+	}
+}
+
+func TestRefreshDoesNotBlockWhileTheTokenIsValid(t *testing.T) {
+	release := make(chan struct{})
+	provider := newTokenProvider(&types.AuthConfig{Type: types.OAuth2ClientCredentials}, nil, func(previous *token) (*token, error) {
+		<-release
+		return &token{AccessToken: "second", ExpiresIn: time.Hour}, nil
+	})
+	provider.current = &token{AccessToken: "first"}
+	provider.refreshAt = time.Now().Add(-time.Second)
+	provider.expiresAt = time.Now().Add(time.Minute)
+
+	refreshed := make(chan string)
+	go func() {
+		accessToken, _ := provider.token()
+		refreshed <- accessToken
+	}()
+	for {
+		provider.mu.Lock()
+		started := provider.fetching != nil
+		provider.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if accessToken, err := provider.token(); err != nil || accessToken != "first" {
+		t.Errorf("during the refresh got %q, %v, want first", accessToken, err)
+	}
+	close(release)
+	if accessToken := <-refreshed; accessToken != "second" {
+		t.Errorf("refresh returned %q, want second", accessToken)
+	}
+	if accessToken, _ := provider.token(); accessToken != "second" {
+		t.Errorf("after the refresh got %q, want second", accessToken)
+	}
+}
+
+func TestFailedRefreshKeepsTheTokenUntilItExpires(t *testing.T) {
+	var fetches atomic.Int32
+	provider := newTokenProvider(&types.AuthConfig{Type: types.OAuth2ClientCredentials}, nil, func(previous *token) (*token, error) {
+		fetches.Add(1)
+		return nil, errors.New("unavailable")
+	})
+	provider.current = &token{AccessToken: "first"}
+	provider.refreshAt = time.Now().Add(-time.Second)
+	provider.expiresAt = time.Now().Add(time.Minute)
+
+	for i := 0; i < 5; i++ {
+		if accessToken, err := provider.token(); err != nil || accessToken != "first" {
+			t.Errorf("before expiry got %q, %v, want first", accessToken, err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("token fetched %d times before the retry delay, want 1", fetches.Load())
+	}
+	if !provider.refreshAt.After(time.Now()) || provider.refreshAt.After(provider.expiresAt) {
+		t.Errorf("refresh retried at %s, the token expires at %s", provider.refreshAt, provider.expiresAt)
+	}
+	provider.refreshAt = time.Now().Add(-2 * time.Second)
+	provider.expiresAt = time.Now().Add(-time.Second)
+	if _, err := provider.token(); err == nil {
+		t.Error("expected an error once the token expired")
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("token fetched %d times, want 2", fetches.Load())
+	}
+}
+
+func TestFailedRefreshIsRetriedBeforeTheTokenExpires(t *testing.T) {
+	provider := newTokenProvider(&types.AuthConfig{Type: types.OAuth2ClientCredentials}, nil, func(previous *token) (*token, error) {
+		return nil, errors.New("unavailable")
+	})
+	provider.current = &token{AccessToken: "first"}
+	provider.refreshAt = time.Now().Add(-time.Second)
+	provider.expiresAt = time.Now().Add(time.Second)
+
+	if _, err := provider.token(); err != nil {
+		t.Fatal(err)
+	}
+	if !provider.refreshAt.Equal(provider.expiresAt) {
+		t.Errorf("refresh retried at %s, want the expiry %s", provider.refreshAt, provider.expiresAt)
+	}
+}
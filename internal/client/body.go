@@ -0,0 +1,161 @@
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"goload/types"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// buildBody returns the payload of request and the Content-Type it implies,
+// empty when the payload is an inline body.
+func buildBody(request types.HTTPRequest) ([]byte, string, error) {
+	if request.Kind == types.GraphQLKind {
+		return buildGraphQLBody(request)
+	}
+
+	sources := 0
+	for _, set := range []bool{request.Body != "", request.BodyFile != "", request.Form != nil, request.Multipart != nil, request.JSON != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, "", fmt.Errorf("only one of body, body_file, form, multipart and json can be set")
+	}
+
+	switch {
+	case request.BodyFile != "":
+		body, err := renderBodyFile(request.BodyFile)
+		return body, "", err
+	case request.Form != nil:
+		form := url.Values{}
+		for name, value := range request.Form {
+			form.Set(name, value)
+		}
+		return []byte(form.Encode()), "application/x-www-form-urlencoded", nil
+	case request.Multipart != nil:
+		return buildMultipart(request.Multipart)
+	case request.JSON != nil:
+		body, err := json.Marshal(request.JSON)
+		if err != nil {
+			return nil, "", fmt.Errorf("error encoding json body: %s", err)
+		}
+		return body, "application/json", nil
+	default:
+		return []byte(request.Body), "", nil
+	}
+}
+
+func buildMultipart(body *types.MultipartBody) ([]byte, string, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	names := make([]string, 0, len(body.Fields))
+	for name := range body.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writer.WriteField(name, body.Fields[name]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, file := range body.Files {
+		content, err := os.Open(file.Path)
+		if err != nil {
+			return nil, "", fmt.Errorf("error opening multipart file: %s", err)
+		}
+		fileName := file.FileName
+		if fileName == "" {
+			fileName = filepath.Base(file.Path)
+		}
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(file.Field), quoteEscaper.Replace(fileName)))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		_ = content.Close()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buffer.Bytes(), writer.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// bodyTemplates caches the parsed body files, they are read once per run.
+var bodyTemplates sync.Map
+
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"now": func(layout string) string {
+		return time.Now().Format(layout)
+	},
+	"unix": func() int64 {
+		return time.Now().Unix()
+	},
+	"randomInt": func(min int, max int) int {
+		if max <= min {
+			return min
+		}
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(max-min)))
+		return min + int(n.Int64())
+	},
+	"uuid": func() string {
+		var b [16]byte
+		_, _ = rand.Read(b[:])
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+}
+
+// renderBodyFile executes the body file as a text/template. Besides the
+// functions above, the template has no data: {{ uuid }}, {{ env "TOKEN" }}.
+func renderBodyFile(path string) ([]byte, error) {
+	cached, ok := bodyTemplates.Load(path)
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading body file: %s", err)
+		}
+		parsed, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing body file: %s", err)
+		}
+		cached, _ = bodyTemplates.LoadOrStore(path, parsed)
+	}
+
+	var buffer bytes.Buffer
+	if err := cached.(*template.Template).Execute(&buffer, nil); err != nil {
+		return nil, fmt.Errorf("error rendering body file: %s", err)
+	}
+	return buffer.Bytes(), nil
+}
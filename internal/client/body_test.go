@@ -0,0 +1,95 @@
+package client
+
+import (
+	"bytes"
+	"goload/types"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     types.HTTPRequest
+		body        string
+		contentType string
+	}{
+		{"inline", types.HTTPRequest{Body: "raw"}, "raw", ""},
+		{"form", types.HTTPRequest{Form: map[string]string{"b": "2 3", "a": "1&"}}, "a=1%26&b=2+3", "application/x-www-form-urlencoded"},
+		{"json", types.HTTPRequest{JSON: map[string]interface{}{"id": 7, "tags": []string{"x"}}}, `{"id":7,"tags":["x"]}`, "application/json"},
+	}
+	for _, test := range tests {
+		body, contentType, err := buildBody(test.request)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if string(body) != test.body || contentType != test.contentType {
+			t.Errorf("%s: got %q, %q, want %q, %q", test.name, body, contentType, test.body, test.contentType)
+		}
+	}
+
+	if _, _, err := buildBody(types.HTTPRequest{Body: "raw", Form: map[string]string{"a": "1"}}); err == nil {
+		t.Error("expected an error when several bodies are set")
+	}
+}
+
+func TestBodyFileIsRenderedAsATemplate(t *testing.T) {
+	t.Setenv("BODY_TEST_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(path, []byte(`{"token":"{{ env "BODY_TEST_TOKEN" }}","id":"{{ uuid }}"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := buildBody(types.HTTPRequest{BodyFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, _ := buildBody(types.HTTPRequest{BodyFile: path})
+	if !strings.HasPrefix(string(first), `{"token":"secret","id":"`) {
+		t.Errorf("unexpected body %s", first)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("the template should be rendered again on each request")
+	}
+}
+
+func TestMultipartBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "avatar.png")
+	if err := os.WriteFile(path, []byte("PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	body, contentType, err := buildBody(types.HTTPRequest{Multipart: &types.MultipartBody{
+		Fields: map[string]string{"user": "alice"},
+		Files:  []types.MultipartFile{{Field: "avatar", Path: path, ContentType: "image/png"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	field, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := io.ReadAll(field)
+	if field.FormName() != "user" || string(value) != "alice" {
+		t.Errorf("got field %s=%s, want user=alice", field.FormName(), value)
+	}
+	file, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(file)
+	if file.FormName() != "avatar" || file.FileName() != "avatar.png" || file.Header.Get("Content-Type") != "image/png" || string(content) != "PNG" {
+		t.Errorf("unexpected file part %v %s", file.Header, content)
+	}
+}
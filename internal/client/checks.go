@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"io"
+)
+
+// BodyCheck is a check evaluated while the response body streams through it,
+// so that checking a body never requires keeping it in memory.
+type BodyCheck interface {
+	io.Writer
+	Passed() bool
+}
+
+// ContainsCheck passes when the body contains Pattern. Only the last
+// len(Pattern)-1 bytes are kept between writes, to match across chunks.
+type ContainsCheck struct {
+	Pattern []byte
+	tail    []byte
+	found   bool
+}
+
+func NewContainsCheck(pattern string) *ContainsCheck {
+	return &ContainsCheck{Pattern: []byte(pattern)}
+}
+
+func (c *ContainsCheck) Write(p []byte) (int, error) {
+	if c.found || len(c.Pattern) == 0 {
+		c.found = true
+		return len(p), nil
+	}
+
+	keep := len(c.Pattern) - 1
+	// A match spanning the previous chunk and this one starts in the tail
+	boundary := append(c.tail, p[:min(len(p), keep)]...)
+	if bytes.Contains(boundary, c.Pattern) || bytes.Contains(p, c.Pattern) {
+		c.found = true
+		c.tail = nil
+		return len(p), nil
+	}
+
+	if len(p) >= keep {
+		c.tail = append(c.tail[:0], p[len(p)-keep:]...)
+	} else {
+		c.tail = append(c.tail, p...)
+		c.tail = c.tail[max(len(c.tail)-keep, 0):]
+	}
+	return len(p), nil
+}
+
+func (c *ContainsCheck) Passed() bool {
+	return c.found || len(c.Pattern) == 0
+}
+
+// bodySink keeps the part of the body selected by the body mode.
+type bodySink struct {
+	buffer bytes.Buffer
+	limit  int64 // -1 keeps everything
+}
+
+func (s *bodySink) Write(p []byte) (int, error) {
+	if s.limit < 0 {
+		return s.buffer.Write(p)
+	}
+	if remaining := s.limit - int64(s.buffer.Len()); remaining > 0 {
+		s.buffer.Write(p[:min(int64(len(p)), remaining)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"goload/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContainsCheckMatchesAcrossChunks(t *testing.T) {
+	body := strings.Repeat("x", 100) + `"status":"ok"` + strings.Repeat("y", 100)
+	for _, chunkSize := range []int{1, 3, 7, 64, len(body)} {
+		check := NewContainsCheck(`"status":"ok"`)
+		for i := 0; i < len(body); i += chunkSize {
+			_, _ = check.Write([]byte(body[i:min(i+chunkSize, len(body))]))
+		}
+		if !check.Passed() {
+			t.Errorf("chunks of %d: pattern not found", chunkSize)
+		}
+	}
+	check := NewContainsCheck("missing")
+	_, _ = check.Write([]byte(body))
+	if check.Passed() {
+		t.Error("unexpected match")
+	}
+}
+
+func TestResponseBodyModes(t *testing.T) {
+	payload := strings.Repeat("0123456789", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		config types.ResponseBodyConfig
+		body   string
+	}{
+		{types.ResponseBodyConfig{Mode: types.FullBody}, payload},
+		{types.ResponseBodyConfig{Mode: types.PrefixBody, MaxBytes: 12}, payload[:12]},
+		{types.ResponseBodyConfig{Mode: types.DiscardBody}, ""},
+	}
+	for _, test := range tests {
+		client := NewClient(Options{ResponseBody: test.config})
+		check := NewContainsCheck("89012")
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		response, err := client.ExecuteRequest(req, check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Body != test.body {
+			t.Errorf("%s: kept %d bytes, want %d", test.config.Mode, len(response.Body), len(test.body))
+		}
+		if !check.Passed() {
+			t.Errorf("%s: the check did not see the whole body", test.config.Mode)
+		}
+		if response.NetworkMetric == nil || response.NetworkMetric.BytesRecv < int64(len(payload)) {
+			t.Errorf("%s: unexpected network metric %+v", test.config.Mode, response.NetworkMetric)
+		}
+	}
+}
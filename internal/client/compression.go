@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultAcceptEncoding is what net/http advertises when it decompresses
+// responses transparently; the client decompresses them itself instead so
+// the wire size stays visible.
+const defaultAcceptEncoding = "gzip"
+
+// compressBody encodes body with one of the gzip, deflate, br and zstd
+// content codings.
+func compressBody(encoding string, body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "deflate":
+		writer = zlib.NewWriter(&buffer)
+	case "br":
+		writer = brotli.NewWriter(&buffer)
+	case "zstd":
+		zstdWriter, err := zstd.NewWriter(&buffer)
+		if err != nil {
+			return nil, err
+		}
+		writer = zstdWriter
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", encoding)
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeBody wraps body with the decoders of the Content-Encoding header,
+// undoing the codings in the reverse order they were applied. An empty body
+// is left as is. The returned close function releases the decoders.
+func decodeBody(contentEncoding string, body io.Reader) (io.Reader, func(), error) {
+	var closers []func()
+	closeAll := func() {
+		for _, closeDecoder := range closers {
+			closeDecoder()
+		}
+	}
+
+	// Bodies of HEAD, 204 and 304 responses are empty whatever their coding
+	buffered := bufio.NewReader(body)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return buffered, closeAll, nil
+	}
+
+	encodings := strings.Split(contentEncoding, ",")
+	var reader io.Reader = buffered
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			gzipReader, err := gzip.NewReader(reader)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			closers = append(closers, func() { _ = gzipReader.Close() })
+			reader = gzipReader
+		case "deflate":
+			// Servers disagree on deflate, accept both zlib wrapped and raw streams
+			buffered := bufio.NewReader(reader)
+			header, _ := buffered.Peek(2)
+			if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+				zlibReader, err := zlib.NewReader(buffered)
+				if err != nil {
+					closeAll()
+					return nil, nil, err
+				}
+				closers = append(closers, func() { _ = zlibReader.Close() })
+				reader = zlibReader
+			} else {
+				flateReader := flate.NewReader(buffered)
+				closers = append(closers, func() { _ = flateReader.Close() })
+				reader = flateReader
+			}
+		case "br":
+			reader = brotli.NewReader(reader)
+		case "zstd":
+			zstdReader, err := zstd.NewReader(reader)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			closers = append(closers, zstdReader.Close)
+			reader = zstdReader
+		default:
+			closeAll()
+			return nil, nil, fmt.Errorf("unsupported content encoding: %s", encodings[i])
+		}
+	}
+	return reader, closeAll, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
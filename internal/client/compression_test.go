@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeBody(t *testing.T) {
+	payload := []byte(`{"items":[1,2,3],"next":"/page/2"}`)
+	rawDeflate := func() []byte {
+		var buffer bytes.Buffer
+		writer, _ := flate.NewWriter(&buffer, flate.DefaultCompression)
+		_, _ = writer.Write(payload)
+		_ = writer.Close()
+		return buffer.Bytes()
+	}
+	compress := func(encoding string, body []byte) []byte {
+		compressed, err := compressBody(encoding, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return compressed
+	}
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", payload},
+		{"identity", payload},
+		{"gzip", compress("gzip", payload)},
+		{"x-gzip", compress("gzip", payload)},
+		{"deflate", compress("deflate", payload)},
+		{"deflate", rawDeflate()},
+		{"br", compress("br", payload)},
+		{"zstd", compress("zstd", payload)},
+		{"gzip, br", compress("br", compress("gzip", payload))},
+	}
+	for _, test := range tests {
+		reader, closeDecoders, err := decodeBody(test.encoding, bytes.NewReader(test.body))
+		if err != nil {
+			t.Errorf("%q: %s", test.encoding, err)
+			continue
+		}
+		decoded, err := io.ReadAll(reader)
+		closeDecoders()
+		if err != nil || !bytes.Equal(decoded, payload) {
+			t.Errorf("%q: got %q, %v", test.encoding, decoded, err)
+		}
+	}
+}
+
+func TestDecodeEmptyBody(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", "br", "zstd", "gzip, zstd"} {
+		reader, closeDecoders, err := decodeBody(encoding, bytes.NewReader(nil))
+		if err != nil {
+			t.Errorf("%q: %s", encoding, err)
+			continue
+		}
+		decoded, err := io.ReadAll(reader)
+		closeDecoders()
+		if err != nil || len(decoded) != 0 {
+			t.Errorf("%q: got %q, %v", encoding, decoded, err)
+		}
+	}
+	if _, _, err := decodeBody("compress", bytes.NewReader([]byte("x"))); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+}
+
+func TestEncodedResponsesWithoutBodySucceed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		switch r.URL.Path {
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("Content-Length", "0")
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(Options{})
+	for _, target := range []struct{ method, path string }{
+		{http.MethodHead, "/"},
+		{http.MethodGet, "/no-content"},
+		{http.MethodGet, "/not-modified"},
+		{http.MethodGet, "/empty"},
+	} {
+		req, _ := http.NewRequest(target.method, server.URL+target.path, nil)
+		response, err := client.ExecuteRequest(req)
+		if err != nil {
+			t.Errorf("%s %s: %s", target.method, target.path, err)
+			continue
+		}
+		if response.RequestMetric.Failed {
+			t.Errorf("%s %s: counted as failed: %v", target.method, target.path, response.Error)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"goload/types"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultDNSPort = "53"
+
+// dialers holds the dialer of each DNS config, shared by all the VUs so that
+// they share the DNS cache and the round-robin over the addresses of a host.
+var dialers sync.Map
+
+// dialer resolves hosts with the DNS config before connecting, trying the
+// addresses of a host in turn, starting with the next one on every dial.
+type dialer struct {
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	hosts    map[string][]string
+	cacheTTL time.Duration
+	cache    map[string]resolvedHost
+	cacheMu  sync.Mutex
+	next     atomic.Uint64
+}
+
+type resolvedHost struct {
+	addresses []string
+	expires   time.Time
+}
+
+func dialerFor(config *types.DNSConfig) *dialer {
+	if cached, ok := dialers.Load(config); ok {
+		return cached.(*dialer)
+	}
+	d := &dialer{
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		resolver: net.DefaultResolver,
+		hosts:    make(map[string][]string),
+		cacheTTL: config.CacheTTL,
+		cache:    make(map[string]resolvedHost),
+	}
+	for host, addresses := range config.Hosts {
+		d.hosts[strings.ToLower(host)] = addresses
+	}
+	switch config.Resolver {
+	case "":
+	case "go":
+		d.resolver = &net.Resolver{PreferGo: true}
+	default:
+		server := config.Resolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, defaultDNSPort)
+		}
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				return d.dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	cached, _ := dialers.LoadOrStore(config, d)
+	return cached.(*dialer)
+}
+
+func (d *dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	addresses, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	start := int(d.next.Add(1) % uint64(len(addresses)))
+	var firstErr error
+	for i := range addresses {
+		ip := addresses[(start+i)%len(addresses)]
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// resolve returns the addresses of host, from the overrides, the cache or the
+// resolver. LookupIPAddr reports the lookups to the request trace itself.
+func (d *dialer) resolve(ctx context.Context, host string) ([]string, error) {
+	if addresses, ok := d.hosts[strings.ToLower(host)]; ok {
+		return addresses, nil
+	}
+	if d.cacheTTL > 0 {
+		d.cacheMu.Lock()
+		cached, ok := d.cache[host]
+		d.cacheMu.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.addresses, nil
+		}
+	}
+
+	ips, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
+	}
+	if d.cacheTTL > 0 {
+		d.cacheMu.Lock()
+		d.cache[host] = resolvedHost{addresses: addresses, expires: time.Now().Add(d.cacheTTL)}
+		d.cacheMu.Unlock()
+	}
+	return addresses, nil
+}
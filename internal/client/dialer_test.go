@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"goload/types"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLookupsAreTracedOnce(t *testing.T) {
+	d := dialerFor(&types.DNSConfig{Resolver: "go"})
+	starts, dones := 0, 0
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { starts++ },
+		DNSDone:  func(httptrace.DNSDoneInfo) { dones++ },
+	})
+	if _, err := d.resolve(ctx, "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if starts != 1 || dones != 1 {
+		t.Errorf("got %d DNSStart and %d DNSDone, want 1", starts, dones)
+	}
+}
+
+func TestHostOverridesFallBackToTheNextAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(serverURL.Host)
+
+	// Nothing listens on 127.0.0.2, every other dial starts there
+	client := newTestClient(Options{DNS: &types.DNSConfig{
+		Hosts: map[string][]string{"API.example.test": {"127.0.0.2", "127.0.0.1"}},
+	}})
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://api.example.test:"+port+"/", nil)
+		response, err := client.ExecuteRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Body != "api.example.test:"+port {
+			t.Errorf("got host %q", response.Body)
+		}
+		if response.RequestMetric.Timing.DNS != 0 {
+			t.Errorf("overridden hosts are not looked up, got %s", response.RequestMetric.Timing.DNS)
+		}
+		client.HttpClient.CloseIdleConnections()
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	d := dialerFor(&types.DNSConfig{Resolver: "go", CacheTTL: time.Minute})
+	if _, err := d.resolve(context.Background(), "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	lookups := 0
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { lookups++ },
+	})
+	if _, err := d.resolve(ctx, "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 0 {
+		t.Errorf("cached host looked up %d times", lookups)
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"goload/types"
+	"io"
+)
+
+func buildGraphQLBody(request types.HTTPRequest) ([]byte, string, error) {
+	if request.GraphQL == nil || request.GraphQL.Query == "" {
+		return nil, "", fmt.Errorf("graphql request requires a query")
+	}
+	if request.Body != "" || request.BodyFile != "" || request.Form != nil || request.Multipart != nil || request.JSON != nil {
+		return nil, "", fmt.Errorf("graphql request cannot have another body")
+	}
+	body, err := json.Marshal(request.GraphQL)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding graphql body: %s", err)
+	}
+	return body, "application/json", nil
+}
+
+// GraphQLErrorsCheck counts the entries of the top-level "errors" array of a
+// GraphQL response. The body is decoded as it streams, the other members
+// being skipped token by token, so it is never held in memory.
+type GraphQLErrorsCheck struct {
+	writer  *io.PipeWriter
+	done    chan struct{}
+	errors  int
+	invalid bool
+}
+
+func NewGraphQLErrorsCheck() *GraphQLErrorsCheck {
+	reader, writer := io.Pipe()
+	check := &GraphQLErrorsCheck{
+		writer: writer,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(check.done)
+		errors, err := decodeGraphQLErrors(json.NewDecoder(reader))
+		check.errors = errors
+		check.invalid = err != nil
+		// Let the writer finish whatever follows the document
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+	return check
+}
+
+func (c *GraphQLErrorsCheck) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+// Errors returns the number of errors of the response, and whether the body
+// was a GraphQL response at all. It must be called once the body was written.
+func (c *GraphQLErrorsCheck) Errors() (int, bool) {
+	_ = c.writer.Close()
+	<-c.done
+	return c.errors, !c.invalid
+}
+
+func (c *GraphQLErrorsCheck) Passed() bool {
+	errors, valid := c.Errors()
+	return valid && errors == 0
+}
+
+func decodeGraphQLErrors(decoder *json.Decoder) (int, error) {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return 0, err
+	}
+	errors := 0
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return errors, err
+		}
+		if key != "errors" {
+			if err := skipValue(decoder); err != nil {
+				return errors, err
+			}
+			continue
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return errors, err
+		}
+		if token == nil {
+			continue
+		}
+		if token != json.Delim('[') {
+			return errors, fmt.Errorf("errors is not an array")
+		}
+		for decoder.More() {
+			if err := skipValue(decoder); err != nil {
+				return errors, err
+			}
+			errors++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return errors, err
+		}
+	}
+	return errors, expectDelim(decoder, '}')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s, got %v", delim, token)
+	}
+	return nil
+}
+
+// skipValue consumes the next value, however deeply nested.
+func skipValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
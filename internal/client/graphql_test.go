@@ -0,0 +1,61 @@
+package client
+
+import (
+	"goload/types"
+	"testing"
+)
+
+func TestBuildGraphQLBody(t *testing.T) {
+	body, contentType, err := buildBody(types.HTTPRequest{
+		Kind: types.GraphQLKind,
+		GraphQL: &types.GraphQLRequest{
+			Query:         "query User($id: ID!) { user(id: $id) { name } }",
+			Variables:     map[string]interface{}{"id": "42"},
+			OperationName: "User",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"query":"query User($id: ID!) { user(id: $id) { name } }","variables":{"id":"42"},"operationName":"User"}`
+	if string(body) != want || contentType != "application/json" {
+		t.Errorf("got %s, %q", body, contentType)
+	}
+
+	for _, request := range []types.HTTPRequest{
+		{Kind: types.GraphQLKind},
+		{Kind: types.GraphQLKind, GraphQL: &types.GraphQLRequest{Query: "{ a }"}, Body: "{}"},
+	} {
+		if _, _, err := buildBody(request); err == nil {
+			t.Errorf("expected an error for %+v", request)
+		}
+	}
+}
+
+func TestGraphQLErrorsCheck(t *testing.T) {
+	tests := []struct {
+		body   string
+		errors int
+		valid  bool
+	}{
+		{`{"data":{"user":{"id":1,"tags":["a",{"b":[1,2]}]}}}`, 0, true},
+		{`{"data":null,"errors":[{"message":"denied","path":["user"]},{"message":"timeout"}]}`, 2, true},
+		{`{"errors":null,"data":{}}`, 0, true},
+		{`{"errors":"denied"}`, 0, false},
+		{`<html>Bad gateway</html>`, 0, false},
+	}
+	for _, test := range tests {
+		check := NewGraphQLErrorsCheck()
+		// Written in small chunks, as the body streams
+		for i := 0; i < len(test.body); i += 7 {
+			_, _ = check.Write([]byte(test.body[i:min(i+7, len(test.body))]))
+		}
+		errors, valid := check.Errors()
+		if errors != test.errors || valid != test.valid {
+			t.Errorf("%s: got %d, %t, want %d, %t", test.body, errors, valid, test.errors, test.valid)
+		}
+		if passed := check.Passed(); passed != (test.valid && test.errors == 0) {
+			t.Errorf("%s: passed %t", test.body, passed)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"goload/types"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type descriptorResolver interface {
+	FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+// grpcMethods caches the resolved method descriptors, shared by all VUs.
+var grpcMethods sync.Map
+
+// ExecuteGRPC calls the method described by request with the request
+// message(s) and header, sent as metadata. The call is unary or streaming
+// depending on the method descriptor.
+func (c *Client) ExecuteGRPC(ctx context.Context, request *types.GRPCRequest, header http.Header) (*types.HTTPResponse, error) {
+	fullMethod := "/" + strings.TrimPrefix(request.Method, "/")
+	metric := &types.GRPCMetric{Method: fullMethod}
+	network := &types.NetworkMetric{}
+	response := &types.HTTPResponse{
+		FinalURL:      request.Target + fullMethod,
+		RequestMetric: &types.RequestMetric{Protocol: "grpc"},
+		NetworkMetric: network,
+		GRPC:          metric,
+	}
+
+	conn, method, err := c.prepareGRPC(ctx, request)
+	if err == nil {
+		// The connection and the method are ready, only the call is timed
+		startTime := time.Now()
+		err = c.invokeGRPC(ctx, conn, method, request, fullMethod, header, response)
+		response.RequestMetric.Duration = time.Since(startTime)
+	} else {
+		response.RequestMetric.NotSent = true
+	}
+
+	code := status.Code(err)
+	metric.Code = code.String()
+	response.StatusCode = int(code)
+	response.RequestMetric.StatusCode = int(code)
+	if err != nil {
+		response.Error = err
+		response.RequestMetric.Failed = true
+	}
+	return response, err
+}
+
+// prepareGRPC returns the connection to the request target, connected, and
+// the descriptor of the called method.
+func (c *Client) prepareGRPC(ctx context.Context, request *types.GRPCRequest) (*grpc.ClientConn, protoreflect.MethodDescriptor, error) {
+	if c.HttpClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.HttpClient.Timeout)
+		defer cancel()
+	}
+	conn, err := c.grpcConn(request)
+	if err != nil {
+		return nil, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	method, err := resolveGRPCMethod(ctx, conn, request)
+	if err != nil {
+		return nil, nil, status.Error(codes.Unimplemented, err.Error())
+	}
+	// The connection is lazy, dial it before the call
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			return nil, nil, status.Errorf(codes.Unavailable, "error connecting to %s: %s", request.Target, ctx.Err())
+		}
+	}
+	return conn, method, nil
+}
+
+func (c *Client) invokeGRPC(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, request *types.GRPCRequest, fullMethod string, header http.Header, response *types.HTTPResponse) error {
+	messages := request.Messages
+	if len(messages) == 0 {
+		messages = []string{request.Message}
+	}
+	if !method.IsStreamingClient() && len(messages) > 1 {
+		return status.Errorf(codes.InvalidArgument, "%s is not a client streaming method", fullMethod)
+	}
+
+	pairs := metadata.MD{}
+	for name, values := range header {
+		pairs.Append(strings.ToLower(name), values...)
+	}
+	for name, value := range request.Metadata {
+		pairs.Append(strings.ToLower(name), value)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, pairs)
+	if c.HttpClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.HttpClient.Timeout)
+		defer cancel()
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{
+		ServerStreams: method.IsStreamingServer(),
+		ClientStreams: method.IsStreamingClient(),
+	}, fullMethod)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		input := dynamicpb.NewMessage(method.Input())
+		if strings.TrimSpace(message) != "" {
+			if err := protojson.Unmarshal([]byte(message), input); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid request message: %s", err)
+			}
+		}
+		if err := stream.SendMsg(input); err != nil {
+			if err == io.EOF {
+				// The server ended the call, its status is returned by RecvMsg
+				break
+			}
+			return err
+		}
+		response.GRPC.MessagesSent++
+		response.NetworkMetric.BytesSent += int64(proto.Size(input))
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	sink := c.bodySink()
+	for {
+		output := dynamicpb.NewMessage(method.Output())
+		err := stream.RecvMsg(output)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		response.GRPC.MessagesReceived++
+		size := int64(proto.Size(output))
+		response.NetworkMetric.BytesRecv += size
+		response.NetworkMetric.BytesRecvDecoded += size
+		if c.ResponseBody.Mode != types.DiscardBody {
+			encoded, _ := protojson.Marshal(output)
+			if response.GRPC.MessagesReceived > 1 {
+				_, _ = sink.Write([]byte("\n"))
+			}
+			_, _ = sink.Write(encoded)
+		}
+		if !method.IsStreamingServer() {
+			break
+		}
+	}
+	response.Body = sink.buffer.String()
+	return nil
+}
+
+// grpcConn returns the connection of the client to the request target, a VU
+// keeping a single connection per target.
+func (c *Client) grpcConn(request *types.GRPCRequest) (*grpc.ClientConn, error) {
+	c.grpcMu.Lock()
+	defer c.grpcMu.Unlock()
+	if conn, ok := c.grpcConns[request.Target]; ok {
+		return conn, nil
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if request.TLS {
+		transportCredentials = credentials.NewTLS(&tls.Config{InsecureSkipVerify: request.SkipVerify})
+	}
+	conn, err := grpc.NewClient(request.Target, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, err
+	}
+	if c.grpcConns == nil {
+		c.grpcConns = make(map[string]*grpc.ClientConn)
+	}
+	c.grpcConns[request.Target] = conn
+	return conn, nil
+}
+
+// Close releases the connections kept by the client.
+func (c *Client) Close() {
+	c.grpcMu.Lock()
+	defer c.grpcMu.Unlock()
+	for target, conn := range c.grpcConns {
+		_ = conn.Close()
+		delete(c.grpcConns, target)
+	}
+	c.HttpClient.CloseIdleConnections()
+}
+
+func resolveGRPCMethod(ctx context.Context, conn *grpc.ClientConn, request *types.GRPCRequest) (protoreflect.MethodDescriptor, error) {
+	key := request.Target + "|" + request.DescriptorSet + "|" + strings.Join(request.ProtoFiles, ",") + "|" + request.Method
+	if cached, ok := grpcMethods.Load(key); ok {
+		return cached.(protoreflect.MethodDescriptor), nil
+	}
+
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(request.Method, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("method must be package.Service/Method, got %s", request.Method)
+	}
+
+	var resolver descriptorResolver
+	var err error
+	switch {
+	case request.DescriptorSet != "":
+		resolver, err = loadDescriptorSet(request.DescriptorSet)
+	case len(request.ProtoFiles) > 0:
+		resolver, err = compileProtoFiles(ctx, request.ProtoFiles, request.ImportPaths)
+	default:
+		resolver, err = reflectService(ctx, conn, serviceName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := resolver.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %s", serviceName, err)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in %s", methodName, serviceName)
+	}
+	grpcMethods.Store(key, method)
+	return method, nil
+}
+
+func loadDescriptorSet(path string) (descriptorResolver, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading descriptor set: %s", err)
+	}
+	var descriptorSet descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &descriptorSet); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %s", err)
+	}
+	return protodesc.NewFiles(&descriptorSet)
+}
+
+func compileProtoFiles(ctx context.Context, files []string, importPaths []string) (descriptorResolver, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	compiled, err := compiler.Compile(ctx, files...)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling proto files: %s", err)
+	}
+	return compiled.AsResolver(), nil
+}
+
+// reflectService downloads the file defining serviceName, and the files it
+// depends on, from the server reflection service.
+func reflectService(ctx context.Context, conn *grpc.ClientConn, serviceName string) (descriptorResolver, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection unavailable: %s", err)
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	fetch := func(request *reflectionpb.ServerReflectionRequest) ([]*descriptorpb.FileDescriptorProto, error) {
+		if err := stream.Send(request); err != nil {
+			return nil, err
+		}
+		response, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if errorResponse := response.GetErrorResponse(); errorResponse != nil {
+			return nil, fmt.Errorf("server reflection: %s", errorResponse.GetErrorMessage())
+		}
+		var files []*descriptorpb.FileDescriptorProto
+		for _, encoded := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(encoded, file); err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	}
+
+	pending, err := fetch(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	})
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]*descriptorpb.FileDescriptorProto{}
+	for len(pending) > 0 {
+		file := pending[0]
+		pending = pending[1:]
+		if _, ok := known[file.GetName()]; ok {
+			continue
+		}
+		known[file.GetName()] = file
+		for _, dependency := range file.GetDependency() {
+			if _, ok := known[dependency]; ok {
+				continue
+			}
+			files, err := fetch(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dependency},
+			})
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, files...)
+		}
+	}
+
+	descriptorSet := &descriptorpb.FileDescriptorSet{}
+	for _, file := range known {
+		descriptorSet.File = append(descriptorSet.File, file)
+	}
+	return protodesc.NewFiles(descriptorSet)
+}
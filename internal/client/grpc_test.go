@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"goload/types"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func grpcServer(t *testing.T, options ...grpc.ServerOption) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(options...)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestGRPCUnaryCallThroughReflection(t *testing.T) {
+	target := grpcServer(t)
+	client := newTestClient(Options{})
+	defer client.Close()
+
+	response, err := client.ExecuteGRPC(context.Background(), &types.GRPCRequest{
+		Target:  target,
+		Method:  "grpc.health.v1.Health/Check",
+		Message: `{"service":"orders"}`,
+	}, http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GRPC.Code != "OK" || response.GRPC.MessagesSent != 1 || response.GRPC.MessagesReceived != 1 {
+		t.Errorf("unexpected metric %+v", response.GRPC)
+	}
+	if !strings.Contains(response.Body, "NOT_SERVING") {
+		t.Errorf("unexpected body %s", response.Body)
+	}
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	target := grpcServer(t)
+	client := newTestClient(Options{})
+	defer client.Close()
+
+	tests := []struct {
+		request types.GRPCRequest
+		code    string
+	}{
+		{types.GRPCRequest{Method: "grpc.health.v1.Health/Check", Message: `{"service":"unknown"}`}, "NotFound"},
+		{types.GRPCRequest{Method: "grpc.health.v1.Health/Missing"}, "Unimplemented"},
+		{types.GRPCRequest{Method: "grpc.health.v1.Health/Check", Message: `{"name":1}`}, "InvalidArgument"},
+		{types.GRPCRequest{Method: "grpc.health.v1.Health/Check", Messages: []string{"{}", "{}"}}, "InvalidArgument"},
+	}
+	for _, test := range tests {
+		test.request.Target = target
+		response, err := client.ExecuteGRPC(context.Background(), &test.request, http.Header{})
+		if err == nil || response.GRPC.Code != test.code || !response.RequestMetric.Failed {
+			t.Errorf("%s %v: got code %s, %v, want %s", test.request.Method, test.request.Messages, response.GRPC.Code, err, test.code)
+		}
+	}
+}
+
+func TestGRPCDurationExcludesMethodResolution(t *testing.T) {
+	// Reflection is slow, the call is not
+	slowReflection := grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.Contains(info.FullMethod, "reflection") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return handler(srv, stream)
+	})
+	target := grpcServer(t, slowReflection)
+	client := newTestClient(Options{})
+	defer client.Close()
+
+	response, err := client.ExecuteGRPC(context.Background(), &types.GRPCRequest{
+		Target:  target,
+		Method:  "grpc.health.v1.Health/Check",
+		Message: `{"service":"orders"}`,
+	}, http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.RequestMetric.Duration <= 0 || response.RequestMetric.Duration >= 200*time.Millisecond {
+		t.Errorf("duration %s includes the method resolution", response.RequestMetric.Duration)
+	}
+
+	response, err = client.ExecuteGRPC(context.Background(), &types.GRPCRequest{
+		Target: target,
+		Method: "grpc.health.v1.Health/Missing",
+	}, http.Header{})
+	if err == nil || !response.RequestMetric.NotSent || response.RequestMetric.Duration != 0 {
+		t.Errorf("unresolved method was timed: %+v, %v", response.RequestMetric, err)
+	}
+}
@@ -1,16 +1,125 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"goload/types"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type Client struct {
-	HttpClient *http.Client
+	HttpClient   *http.Client
+	Redirect     *types.RedirectPolicy
+	ResponseBody types.ResponseBodyConfig
+	grpcConns    map[string]*grpc.ClientConn
+	grpcMu       sync.Mutex
+	proxies      []*url.URL
+	proxyIndex   atomic.Int64
+}
+
+// Options configure NewClient. The auto body mode must be resolved by the
+// caller, which knows whether its checks need the full body.
+type Options struct {
+	Timeout      time.Duration
+	Redirect     *types.RedirectPolicy
+	CookieJar    bool
+	ResponseBody types.ResponseBodyConfig
+	Proxy        *types.ProxyConfig
+	ProxyOffset  int // Index of the first proxy of the list used by the client
+	DNS          *types.DNSConfig
+}
+
+// NewClient builds a Client whose redirects are followed by ExecuteRequest
+// rather than by net/http, so that every hop can be timed individually.
+func NewClient(options Options) *Client {
+	// Responses are decompressed by ExecuteRequest, see decodeBody
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	if options.DNS != nil {
+		transport.DialContext = dialerFor(options.DNS).DialContext
+	}
+
+	c := &Client{
+		HttpClient: &http.Client{
+			Transport: transport,
+			Timeout:   options.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Redirect:     options.Redirect,
+		ResponseBody: options.ResponseBody,
+	}
+	if options.CookieJar {
+		c.ResetCookies()
+	}
+	if options.Proxy != nil {
+		// The config is validated by the caller
+		c.proxies, _ = options.Proxy.URLs()
+		c.proxyIndex.Store(int64(options.ProxyOffset))
+		transport.Proxy = c.proxy
+	}
+	return c
+}
+
+func (c *Client) proxy(*http.Request) (*url.URL, error) {
+	if len(c.proxies) == 0 {
+		return nil, nil
+	}
+	return c.proxies[int(c.proxyIndex.Load()%int64(len(c.proxies)))], nil
+}
+
+// RotateProxy moves the client to the next proxy of its list.
+func (c *Client) RotateProxy() {
+	c.proxyIndex.Add(1)
+}
+
+// proxyFor returns the proxy req is sent through, nil for direct requests.
+func (c *Client) proxyFor(req *http.Request) *url.URL {
+	transport, ok := c.HttpClient.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return nil
+	}
+	proxyURL, _ := transport.Proxy(req)
+	return proxyURL
+}
+
+// ResetCookies replaces the client cookie jar with an empty one.
+func (c *Client) ResetCookies() {
+	jar, _ := cookiejar.New(nil)
+	c.HttpClient.Jar = jar
+}
+
+// SeedCookies stores cookies in the jar as if they had been set by uri.
+// Cookies without a path are made visible to the whole host.
+func (c *Client) SeedCookies(uri string, cookies []types.HTTPClientCookie) error {
+	if c.HttpClient.Jar == nil || len(cookies) == 0 {
+		return nil
+	}
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	httpCookies := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		httpCookie := cookie.HTTPCookie()
+		if httpCookie.Path == "" {
+			httpCookie.Path = "/"
+		}
+		httpCookies = append(httpCookies, httpCookie)
+	}
+	c.HttpClient.Jar.SetCookies(parsedURL, httpCookies)
+	return nil
 }
 
 type RequestOptions struct {
@@ -19,28 +128,53 @@ type RequestOptions struct {
 	Body    string
 }
 
-func (c *Client) ExecuteRequest(req *http.Request) (*types.HTTPResponse, error) {
+// ExecuteRequest sends req and streams the response body through the checks.
+// Only the part of the body selected by the client body mode is returned.
+func (c *Client) ExecuteRequest(req *http.Request, checks ...BodyCheck) (*types.HTTPResponse, error) {
+	req, tracer := traceRequest(req)
+	if c.HttpClient.Timeout > 0 {
+		// The timeout covers the whole redirect chain, every hop inherits it
+		ctx, cancel := context.WithTimeout(req.Context(), c.HttpClient.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
 	startTime := time.Now()
-	resp, err := c.HttpClient.Do(req)
+	resp, hops, err := c.do(c.HttpClient, req)
 	if err != nil {
 		return &types.HTTPResponse{
+			Hops:  hops,
 			Error: err,
 			RequestMetric: &types.RequestMetric{
 				Duration: time.Since(startTime),
+				Timing:   tracer.result(),
 			},
 		}, err
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Read response body, counting the bytes on the wire before decoding
+	wireBody := &countingReader{reader: resp.Body}
+	decodedBody, closeDecoders, err := decodeBody(resp.Header.Get("Content-Encoding"), wireBody)
+	sink := c.bodySink()
+	var decodedSize int64
+	if err == nil {
+		writers := []io.Writer{sink}
+		for _, check := range checks {
+			writers = append(writers, check)
+		}
+		decodedSize, err = io.Copy(io.MultiWriter(writers...), decodedBody)
+		closeDecoders()
+	}
 	if err != nil {
 		return &types.HTTPResponse{
 			StatusCode: resp.StatusCode,
+			FinalURL:   resp.Request.URL.String(),
+			Hops:       hops,
 			Error:      err,
 			RequestMetric: &types.RequestMetric{
 				Duration:   time.Since(startTime),
 				StatusCode: resp.StatusCode,
+				Timing:     tracer.result(),
 			},
 		}, err
 	}
@@ -77,39 +211,179 @@ func (c *Client) ExecuteRequest(req *http.Request) (*types.HTTPResponse, error)
 
 	return &types.HTTPResponse{
 		StatusCode: resp.StatusCode,
-		Body:       string(bodyBytes),
+		Body:       sink.buffer.String(),
+		FinalURL:   resp.Request.URL.String(),
+		Hops:       hops,
 		Headers:    headers,
 		Cookies:    cookies,
 		RequestMetric: &types.RequestMetric{
 			Duration:   duration,
 			StatusCode: resp.StatusCode,
+			Timing:     tracer.result(),
 		},
 		NetworkMetric: &types.NetworkMetric{
-			BytesSent: 0,
-			BytesRecv: int64(len(bodyBytes)),
+			BytesSent:        max(req.ContentLength, 0),
+			BytesRecv:        wireBody.count,
+			BytesRecvDecoded: decodedSize,
 		},
 		Error: nil,
 	}, nil
 }
 
-func CreateRequest(request types.HTTPRequest) (*http.Request, error) {
-	parsedURL, err := url.Parse(request.URI)
+func (c *Client) bodySink() *bodySink {
+	switch c.ResponseBody.Mode {
+	case types.FullBody:
+		return &bodySink{limit: -1}
+	case types.PrefixBody:
+		if c.ResponseBody.MaxBytes > 0 {
+			return &bodySink{limit: c.ResponseBody.MaxBytes}
+		}
+		return &bodySink{limit: types.DefaultBodyPrefixBytes}
+	default:
+		return &bodySink{limit: 0}
+	}
+}
+
+// do sends req and, unless the redirect policy says otherwise, follows the
+// redirect chain it starts. Every exchange is recorded as a hop, the last one
+// being the response that is returned.
+func (c *Client) do(httpClient *http.Client, req *http.Request) (*http.Response, []types.HopMetric, error) {
+	var hops []types.HopMetric
+	for {
+		beginHop(req, c.proxyFor(req))
+		hopStart := time.Now()
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, hops, err
+		}
+		hops = append(hops, types.HopMetric{
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Duration:   time.Since(hopStart),
+		})
+
+		if !c.Redirect.FollowsRedirects() || !isRedirect(resp.StatusCode) {
+			return resp, hops, nil
+		}
+		location, err := resp.Location()
+		if err != nil {
+			// A 3xx without a usable Location header is the final response
+			return resp, hops, nil
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		_ = resp.Body.Close()
+
+		if len(hops) > c.Redirect.MaxRedirectHops() {
+			return nil, hops, fmt.Errorf("stopped after %d redirects", c.Redirect.MaxRedirectHops())
+		}
+		req, err = c.redirectRequest(req, resp.StatusCode, location.String())
+		if err != nil {
+			return nil, hops, err
+		}
+	}
+}
+
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectRequest builds the request for the next hop. 307 and 308 keep the
+// method and body when the policy preserves them; every other redirect turns
+// into a body-less GET, except for HEAD which stays HEAD.
+func (c *Client) redirectRequest(previous *http.Request, statusCode int, location string) (*http.Request, error) {
+	method := previous.Method
+	keepBody := (statusCode == http.StatusTemporaryRedirect || statusCode == http.StatusPermanentRedirect) &&
+		c.Redirect.PreservesMethod()
+	if !keepBody && method != http.MethodHead {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if keepBody && previous.GetBody != nil {
+		reader, err := previous.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body = reader
+	}
+
+	req, err := http.NewRequestWithContext(previous.Context(), method, location, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = previous.Header.Clone()
+	if !keepBody {
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+	}
+	if c.HttpClient.Jar != nil {
+		// The jar adds the cookies of the next hop, including the ones the
+		// redirect response just set
+		req.Header.Del("Cookie")
+	}
+	if req.URL.Hostname() != previous.URL.Hostname() {
+		req.Header.Del("Authorization")
+		req.Header.Del("Www-Authenticate")
+		req.Header.Del("Cookie")
+	}
+	return req, nil
+}
+
+// CreateRequest builds the http.Request described by request, then applies the
+// signers in order, once the payload is final. Authentication is applied as a
+// signer too, ahead of the signatures.
+func CreateRequest(request types.HTTPRequest, signers ...Signer) (*http.Request, error) {
+	method := strings.ToUpper(string(request.Method))
+	if method == "" && request.Kind == types.GraphQLKind {
+		method = http.MethodPost
+	} else if method == "" {
+		method = http.MethodGet
+	}
+
+	body, contentType, err := buildBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Compression != "" && len(body) > 0 {
+		body, err = compressBody(request.Compression, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, request.URI, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	headers := http.Header{}
 	for _, header := range request.Headers {
-		headers.Add(header.Name, header.Value)
+		req.Header.Add(header.Name, header.Value)
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if request.Compression != "" && len(body) > 0 {
+		req.Header.Set("Content-Encoding", strings.ToLower(request.Compression))
+	}
+	if request.AcceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", request.AcceptEncoding)
+	} else if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", defaultAcceptEncoding)
+	}
+	for _, cookie := range request.Cookies {
+		req.AddCookie(cookie.HTTPCookie())
 	}
 
-	req := &http.Request{
-		Method: "GET",
-		URL:    parsedURL,
-		Body:   io.NopCloser(strings.NewReader(request.Body)),
-		Header: headers,
-		Host:   parsedURL.Host,
-		Proto:  "HTTP/1.1",
+	for _, signer := range signers {
+		if err := signer.Sign(req, body); err != nil {
+			return nil, fmt.Errorf("error signing request: %s", err)
+		}
 	}
 
 	return req, nil
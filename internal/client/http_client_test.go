@@ -0,0 +1,155 @@
+package client
+
+import (
+	"goload/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func redirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chain/", func(w http.ResponseWriter, r *http.Request) {
+		left, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+		if left == 0 {
+			_, _ = io.WriteString(w, "done")
+			return
+		}
+		http.Redirect(w, r, "/chain/"+strconv.Itoa(left-1), http.StatusFound)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/echo", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/found", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/echo", http.StatusFound)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, r.Method+" "+string(body))
+	})
+	mux.HandleFunc("/slow/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		left, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/slow/"))
+		if left == 0 {
+			return
+		}
+		http.Redirect(w, r, "/slow/"+strconv.Itoa(left-1), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(options Options) *Client {
+	options.ResponseBody = types.ResponseBodyConfig{Mode: types.FullBody}
+	return NewClient(options)
+}
+
+func TestRedirectChainIsFollowedHopByHop(t *testing.T) {
+	server := redirectServer(t)
+	client := newTestClient(Options{})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/chain/3", nil)
+	response, err := client.ExecuteRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Body != "done" || len(response.Hops) != 4 {
+		t.Fatalf("got body %q after %d hops, want done after 4", response.Body, len(response.Hops))
+	}
+	if !strings.HasSuffix(response.FinalURL, "/chain/0") {
+		t.Errorf("final url %s", response.FinalURL)
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	server := redirectServer(t)
+	follow, preserve := false, false
+
+	tests := []struct {
+		name       string
+		policy     *types.RedirectPolicy
+		path       string
+		wantBody   string
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "307 keeps the method and body", path: "/temporary", wantBody: "POST payload"},
+		{name: "302 turns into a GET", path: "/found", wantBody: "GET "},
+		{name: "307 without preserve_method", policy: &types.RedirectPolicy{PreserveMethod: &preserve}, path: "/temporary", wantBody: "GET "},
+		{name: "not followed", policy: &types.RedirectPolicy{Follow: &follow}, path: "/found", wantStatus: http.StatusFound},
+		{name: "max hops", policy: &types.RedirectPolicy{MaxHops: 2}, path: "/chain/5", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(Options{Redirect: test.policy})
+			req, _ := http.NewRequest(http.MethodPost, server.URL+test.path, strings.NewReader("payload"))
+			response, err := client.ExecuteRequest(req)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantStatus != 0 {
+				if response.StatusCode != test.wantStatus {
+					t.Errorf("got status %d, want %d", response.StatusCode, test.wantStatus)
+				}
+				return
+			}
+			if response.Body != test.wantBody {
+				t.Errorf("got body %q, want %q", response.Body, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestTimeoutCoversTheWholeRedirectChain(t *testing.T) {
+	server := redirectServer(t)
+	// Each hop takes 150ms, under the timeout, but the chain takes 600ms
+	client := newTestClient(Options{Timeout: 400 * time.Millisecond})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/slow/3", nil)
+	start := time.Now()
+	_, err := client.ExecuteRequest(req)
+	if err == nil {
+		t.Fatal("expected the chain to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 550*time.Millisecond {
+		t.Errorf("chain stopped after %s, want about 400ms", elapsed)
+	}
+}
+
+func TestRedirectSendsTheCookiesSetByTheRedirect(t *testing.T) {
+	var received []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "new", Path: "/"})
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Values("Cookie")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := newTestClient(Options{CookieJar: true})
+	if err := client.SeedCookies(server.URL, []types.HTTPClientCookie{{Name: "session", Value: "old"}}); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/login", nil)
+	if _, err := client.ExecuteRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0] != "session=new" {
+		t.Errorf("got cookies %q, want [session=new]", received)
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"goload/types"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectors of the AWS Signature Version 4 test suite, signed with its example
+// credentials on 2015-08-30 12:36:00 UTC.
+func TestSigV4TestSuite(t *testing.T) {
+	const credential = "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		headers       map[string]string
+		body          string
+		authorization string
+	}{
+		{
+			name:          "get-vanilla",
+			method:        http.MethodGet,
+			path:          "/",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-slash",
+			method:        http.MethodGet,
+			path:          "//",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-slash-dot-slash",
+			method:        http.MethodGet,
+			path:          "/./",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-relative-relative",
+			method:        http.MethodGet,
+			path:          "/example1/example2/../..",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-vanilla-query-order-key-case",
+			method:        http.MethodGet,
+			path:          "/?Param2=value2&Param1=value1",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:          "post-vanilla",
+			method:        http.MethodPost,
+			path:          "/",
+			authorization: "SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:          "post-x-www-form-urlencoded",
+			method:        http.MethodPost,
+			path:          "/",
+			headers:       map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:          "Param1=value1",
+			authorization: "SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, "https://example.amazonaws.com"+test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			signer := &SigV4Signer{
+				AccessKey: "AKIDEXAMPLE",
+				SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+				Region:    "us-east-1",
+				Service:   "service",
+				Now:       func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+			}
+			if err := signer.Sign(req, []byte(test.body)); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization"); got != credential+test.authorization {
+				t.Errorf("got %s, want %s", got, credential+test.authorization)
+			}
+		})
+	}
+}
+
+func TestSigV4Path(t *testing.T) {
+	tests := []struct {
+		escapedPath string
+		service     string
+		want        string
+	}{
+		{"", "service", "/"},
+		{"//example//", "service", "/example/"},
+		{"/example space/", "service", "/example%20space/"}, // get-space, the suite sends the path unencoded
+		{"/example%20space/", "service", "/example%2520space/"},
+		{"/a/b/../c", "service", "/a/c"},
+		{"/example%20space/", "s3", "/example%20space/"},
+		{"/bucket//key/../other", "s3", "/bucket//key/../other"},
+	}
+	for _, test := range tests {
+		if got := sigV4Path(test.escapedPath, test.service); got != test.want {
+			t.Errorf("sigV4Path(%q, %q) = %q, want %q", test.escapedPath, test.service, got, test.want)
+		}
+	}
+}
+
+// The RFC 4231 vectors check the algorithms and encodings; the default
+// components vector was computed independently with Python's hmac module.
+func TestHMACSigner(t *testing.T) {
+	now := func() time.Time { return time.Unix(1440938160, 0) }
+	for _, test := range []struct {
+		name   string
+		config types.SigningConfig
+		body   string
+		header string
+		want   string
+	}{
+		{
+			name:   "default components",
+			config: types.SigningConfig{Secret: "secret", KeyID: "key-1"},
+			body:   `{"id":1}`,
+			header: "X-Signature",
+			want:   "6e14e29d6a146c197233f09df6ca4d84da9211bc48abc55b2b5b05fcfccd9b09",
+		},
+		{
+			name:   "rfc 4231 sha256",
+			config: types.SigningConfig{Secret: "Jefe", Components: []string{"body"}, Header: "X-Hub-Signature"},
+			body:   "what do ya want for nothing?",
+			header: "X-Hub-Signature",
+			want:   "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:   "rfc 4231 sha512",
+			config: types.SigningConfig{Secret: "Jefe", Components: []string{"body"}, Algorithm: "SHA512"},
+			body:   "what do ya want for nothing?",
+			header: "X-Signature",
+			want:   "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		},
+		{
+			name:   "rfc 4231 base64",
+			config: types.SigningConfig{Secret: "Jefe", Components: []string{"body"}, Encoding: "base64"},
+			body:   "what do ya want for nothing?",
+			header: "X-Signature",
+			want:   "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Type = types.HMACSigning
+			signer, err := NewSigner(&test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signer.(*HMACSigner).Now = now
+			req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/orders?page=2", strings.NewReader(test.body))
+			if err := signer.Sign(req, []byte(test.body)); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get(test.header); got != test.want {
+				t.Errorf("got signature %s, want %s", got, test.want)
+			}
+			if got := req.Header.Get("X-Timestamp"); got != "1440938160" {
+				t.Errorf("got timestamp %q", got)
+			}
+			if got := req.Header.Get("X-Key-Id"); got != test.config.KeyID {
+				t.Errorf("got key id %q, want %q", got, test.config.KeyID)
+			}
+		})
+	}
+}
+
+func TestHMACSignerRejectsUnknownSettings(t *testing.T) {
+	if _, err := NewSigner(&types.SigningConfig{Type: types.HMACSigning, Secret: "s", Algorithm: "md5"}); err == nil {
+		t.Error("expected an unknown algorithm to be rejected")
+	}
+	signer, err := NewSigner(&types.SigningConfig{Type: types.HMACSigning, Secret: "s", Components: []string{"cookie"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	if err := signer.Sign(req, nil); err == nil {
+		t.Error("expected an unknown component to be rejected")
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"goload/types"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultReadTimeout = 10 * time.Second
+	maxDatagramSize    = 64 * 1024
+)
+
+// ExecuteTCP opens a connection to the request address, sends its payload
+// and reads the reply. The RequestMetric times the whole exchange.
+func (c *Client) ExecuteTCP(ctx context.Context, request *types.TCPRequest) (*types.HTTPResponse, error) {
+	metric := &types.SocketMetric{Protocol: "tcp"}
+	response := newSocketResponse(request.Address, metric)
+	fail := func(err error) (*types.HTTPResponse, error) {
+		metric.TimedOut = errors.Is(err, os.ErrDeadlineExceeded)
+		response.Error = err
+		response.RequestMetric.Failed = true
+		return response, err
+	}
+
+	startTime := time.Now()
+	defer func() { response.RequestMetric.Duration = time.Since(startTime) }()
+	conn, err := c.dialSocket(ctx, "tcp", request.Address)
+	if err == nil && request.TLS {
+		host, _, _ := net.SplitHostPort(request.Address)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: request.SkipVerify})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+		}
+		conn = tlsConn
+	}
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+	metric.ConnectTime = time.Since(startTime)
+	metric.Connected = true
+
+	if request.Payload != "" {
+		if _, err := conn.Write([]byte(request.Payload)); err != nil {
+			return fail(err)
+		}
+		response.NetworkMetric.BytesSent = int64(len(request.Payload))
+	}
+
+	sentTime := time.Now()
+	_ = conn.SetReadDeadline(sentTime.Add(readTimeout(request.ReadTimeout, c.HttpClient.Timeout)))
+	reply, err := readReply(conn, request)
+	metric.ResponseTime = time.Since(sentTime)
+	metric.AwaitedReply = true
+	c.recordReply(response, reply)
+	if err != nil {
+		return fail(err)
+	}
+	return response, nil
+}
+
+// readReply reads up to the delimiter or length of request. When neither is
+// set the reply ends with the connection or the read deadline.
+func readReply(conn net.Conn, request *types.TCPRequest) ([]byte, error) {
+	switch {
+	case request.Length > 0:
+		reply := make([]byte, request.Length)
+		n, err := io.ReadFull(conn, reply)
+		return reply[:n], err
+	case request.Delimiter != "":
+		var reply []byte
+		buffer := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buffer)
+			reply = append(reply, buffer[:n]...)
+			if index := bytes.Index(reply, []byte(request.Delimiter)); index >= 0 {
+				return reply[:index+len(request.Delimiter)], nil
+			}
+			if err == io.EOF {
+				return reply, fmt.Errorf("connection closed before delimiter %q", request.Delimiter)
+			}
+			if err != nil {
+				return reply, err
+			}
+		}
+	default:
+		reply, err := io.ReadAll(conn)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			err = nil
+		}
+		return reply, err
+	}
+}
+
+// ExecuteUDP sends the request payload as a single datagram, and waits for
+// a reply datagram when the request awaits one.
+func (c *Client) ExecuteUDP(ctx context.Context, request *types.UDPRequest) (*types.HTTPResponse, error) {
+	metric := &types.SocketMetric{Protocol: "udp"}
+	response := newSocketResponse(request.Address, metric)
+	fail := func(err error) (*types.HTTPResponse, error) {
+		metric.TimedOut = errors.Is(err, os.ErrDeadlineExceeded)
+		response.Error = err
+		response.RequestMetric.Failed = true
+		return response, err
+	}
+
+	startTime := time.Now()
+	defer func() { response.RequestMetric.Duration = time.Since(startTime) }()
+	conn, err := c.dialSocket(ctx, "udp", request.Address)
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(request.Payload)); err != nil {
+		return fail(err)
+	}
+	response.NetworkMetric.BytesSent = int64(len(request.Payload))
+	if !request.AwaitReply {
+		return response, nil
+	}
+
+	sentTime := time.Now()
+	_ = conn.SetReadDeadline(sentTime.Add(readTimeout(request.ReadTimeout, c.HttpClient.Timeout)))
+	reply := make([]byte, maxDatagramSize)
+	n, err := conn.Read(reply)
+	metric.ResponseTime = time.Since(sentTime)
+	metric.AwaitedReply = true
+	c.recordReply(response, reply[:n])
+	if err != nil {
+		return fail(err)
+	}
+	return response, nil
+}
+
+func newSocketResponse(address string, metric *types.SocketMetric) *types.HTTPResponse {
+	return &types.HTTPResponse{
+		FinalURL:      metric.Protocol + "://" + address,
+		RequestMetric: &types.RequestMetric{Protocol: metric.Protocol},
+		NetworkMetric: &types.NetworkMetric{},
+		Socket:        metric,
+	}
+}
+
+// dialSocket dials with the dialer of the HTTP transport, so that sockets
+// resolve and connect like the other requests.
+func (c *Client) dialSocket(ctx context.Context, network string, address string) (net.Conn, error) {
+	timeout := c.HttpClient.Timeout
+	if timeout == 0 {
+		timeout = defaultDialTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if transport, ok := c.HttpClient.Transport.(*http.Transport); ok && transport.DialContext != nil {
+		return transport.DialContext(ctx, network, address)
+	}
+	dialer := &net.Dialer{}
+	return dialer.DialContext(ctx, network, address)
+}
+
+func (c *Client) recordReply(response *types.HTTPResponse, reply []byte) {
+	response.NetworkMetric.BytesRecv = int64(len(reply))
+	response.NetworkMetric.BytesRecvDecoded = int64(len(reply))
+	if c.ResponseBody.Mode == types.DiscardBody {
+		return
+	}
+	sink := c.bodySink()
+	_, _ = sink.Write(reply)
+	response.Body = sink.buffer.String()
+}
+
+func readTimeout(timeout time.Duration, clientTimeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	if clientTimeout > 0 {
+		return clientTimeout
+	}
+	return defaultReadTimeout
+}
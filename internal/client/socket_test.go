@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"goload/types"
+	"net"
+	"testing"
+	"time"
+)
+
+// lineServer answers every line with "+OK <line>\r\n".
+func lineServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					_, _ = conn.Write([]byte("+OK " + scanner.Text() + "\r\n"))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTCPExchange(t *testing.T) {
+	address := lineServer(t)
+	client := newTestClient(Options{})
+
+	tests := []struct {
+		name    string
+		request types.TCPRequest
+		reply   string
+		wantErr bool
+	}{
+		{name: "delimiter", request: types.TCPRequest{Payload: "PING\n", Delimiter: "\r\n"}, reply: "+OK PING\r\n"},
+		{name: "length", request: types.TCPRequest{Payload: "PING\n", Length: 3}, reply: "+OK"},
+		{name: "timeout", request: types.TCPRequest{Payload: "PING\n", Delimiter: "$", ReadTimeout: 50 * time.Millisecond}, wantErr: true},
+	}
+	for _, test := range tests {
+		test.request.Address = address
+		response, err := client.ExecuteTCP(context.Background(), &test.request)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got %v", test.name, err)
+			continue
+		}
+		if test.wantErr {
+			if !response.Socket.TimedOut || !response.RequestMetric.Failed {
+				t.Errorf("%s: unexpected metric %+v", test.name, response.Socket)
+			}
+			continue
+		}
+		if response.Body != test.reply || response.NetworkMetric.BytesSent != 5 || response.Socket.ConnectTime <= 0 || !response.Socket.Connected || !response.Socket.AwaitedReply {
+			t.Errorf("%s: got %q, %+v", test.name, response.Body, response.NetworkMetric)
+		}
+	}
+}
+
+func TestUDPExchange(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, peer, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(append([]byte("ack "), buffer[:n]...), peer)
+		}
+	}()
+
+	client := newTestClient(Options{})
+	response, err := client.ExecuteUDP(context.Background(), &types.UDPRequest{
+		Address:    conn.LocalAddr().String(),
+		Payload:    "metric:1|c",
+		AwaitReply: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Body != "ack metric:1|c" || response.Socket.Protocol != "udp" || !response.Socket.AwaitedReply || response.Socket.Connected {
+		t.Errorf("got %q, %+v", response.Body, response.Socket)
+	}
+
+	response, err = client.ExecuteUDP(context.Background(), &types.UDPRequest{
+		Address: conn.LocalAddr().String(),
+		Payload: "metric:1|c",
+	})
+	if err != nil || response.Socket.AwaitedReply {
+		t.Errorf("got %v, %+v without await_reply", err, response.Socket)
+	}
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"goload/types"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxSSELineSize = 1 << 20
+
+// ExecuteSSE sends req and listens to the event stream of its response until
+// the stream ends or the limits of stream are reached. The RequestMetric
+// times the response headers; the stream is described by the SSE metric.
+func (c *Client) ExecuteSSE(req *http.Request, stream *types.SSEStream) (*types.HTTPResponse, error) {
+	streamCtx, cancelStream := context.WithCancelCause(req.Context())
+	defer cancelStream(nil)
+	ctx := streamCtx
+	if stream.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stream.Duration)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// The client timeout covers the whole body, it would cut the stream.
+	// It bounds the wait for the headers and the first event instead.
+	streamClient := *c.HttpClient
+	streamClient.Timeout = 0
+	var firstEventTimer *time.Timer
+	var errNoFirstEvent error
+	if timeout := c.HttpClient.Timeout; timeout > 0 {
+		errNoFirstEvent = fmt.Errorf("no event received within %s", timeout)
+		firstEventTimer = time.AfterFunc(timeout, func() { cancelStream(errNoFirstEvent) })
+		defer firstEventTimer.Stop()
+	}
+
+	metric := &types.SSEMetric{}
+	response := &types.HTTPResponse{
+		RequestMetric: &types.RequestMetric{},
+		NetworkMetric: &types.NetworkMetric{},
+		SSE:           metric,
+	}
+	fail := func(err error) (*types.HTTPResponse, error) {
+		if errNoFirstEvent != nil && context.Cause(streamCtx) == errNoFirstEvent {
+			err = errNoFirstEvent
+		}
+		response.Error = err
+		response.RequestMetric.Failed = true
+		return response, err
+	}
+	var expect *regexp.Regexp
+	if stream.Expect != "" {
+		var err error
+		if expect, err = expectPattern(stream.Expect); err != nil {
+			response.RequestMetric.NotSent = true
+			return fail(err)
+		}
+	}
+
+	req, tracer := traceRequest(req)
+	startTime := time.Now()
+	resp, hops, err := c.do(&streamClient, req)
+	response.Hops = hops
+	response.RequestMetric.Duration = time.Since(startTime)
+	response.RequestMetric.Timing = tracer.result()
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	response.StatusCode = resp.StatusCode
+	response.RequestMetric.StatusCode = resp.StatusCode
+	response.FinalURL = resp.Request.URL.String()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail(fmt.Errorf("unexpected status code %d", resp.StatusCode))
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return fail(fmt.Errorf("unexpected content type %q", resp.Header.Get("Content-Type")))
+	}
+
+	wireBody := &countingReader{reader: resp.Body}
+	decodedBody, closeDecoders, err := decodeBody(resp.Header.Get("Content-Encoding"), wireBody)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeDecoders()
+
+	scanner := bufio.NewScanner(decodedBody)
+	scanner.Buffer(make([]byte, 0, 4096), maxSSELineSize)
+	var eventType string
+	var data []string
+	var lastEvent time.Time
+	dispatch := func() {
+		defer func() { eventType, data = "", nil }()
+		if data == nil || (stream.Event != "" && eventType != stream.Event) {
+			return
+		}
+		now := time.Now()
+		if metric.Events == 0 {
+			metric.TimeToFirstEvent = now.Sub(startTime)
+			if firstEventTimer != nil {
+				firstEventTimer.Stop()
+			}
+		} else {
+			metric.Gaps = append(metric.Gaps, now.Sub(lastEvent))
+		}
+		lastEvent = now
+		metric.Events++
+		if expect != nil && !expect.MatchString(strings.Join(data, "\n")) {
+			metric.InvalidEvents++
+		}
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		response.NetworkMetric.BytesRecvDecoded += int64(len(scanner.Bytes())) + 1
+		if line == "" {
+			dispatch()
+			if stream.MaxEvents > 0 && metric.Events >= int64(stream.MaxEvents) {
+				break
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// Comment, used by servers as keep-alive
+		case "event":
+			eventType = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	metric.StreamTime = time.Since(startTime)
+	response.NetworkMetric.BytesRecv = wireBody.count
+	response.NetworkMetric.BytesSent = max(req.ContentLength, 0)
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fail(err)
+	}
+	if metric.Events == 0 {
+		return fail(fmt.Errorf("no event received"))
+	}
+	if metric.InvalidEvents > 0 {
+		return fail(fmt.Errorf("%d events do not match /%s/", metric.InvalidEvents, stream.Expect))
+	}
+	return response, nil
+}
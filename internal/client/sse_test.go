@@ -0,0 +1,141 @@
+package client
+
+import (
+	"fmt"
+	"goload/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sseServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/html" {
+			_, _ = w.Write([]byte("<html></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		_, _ = w.Write([]byte(": keep-alive\n\n"))
+		for i := 1; i <= 5; i++ {
+			event := "price"
+			if i%2 == 0 {
+				event = "heartbeat"
+			}
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: {\"seq\":%d}\n\n", event, i)
+			flusher.Flush()
+		}
+	}))
+}
+
+func TestSSEStream(t *testing.T) {
+	server := sseServer()
+	defer server.Close()
+	client := newTestClient(Options{})
+
+	tests := []struct {
+		name    string
+		path    string
+		stream  types.SSEStream
+		events  int64
+		invalid int64
+		wantErr bool
+	}{
+		{name: "whole stream", stream: types.SSEStream{}, events: 5},
+		{name: "max events", stream: types.SSEStream{MaxEvents: 2}, events: 2},
+		{name: "event type", stream: types.SSEStream{Event: "price"}, events: 3},
+		{name: "expect", stream: types.SSEStream{Expect: `"seq":[1-3]\b`}, events: 5, invalid: 2, wantErr: true},
+		{name: "not a stream", path: "/html", stream: types.SSEStream{}, wantErr: true},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+test.path, nil)
+		response, err := client.ExecuteSSE(req, &test.stream)
+		if (err != nil) != test.wantErr || response.RequestMetric.Failed != test.wantErr {
+			t.Errorf("%s: got %v, failed %t", test.name, err, response.RequestMetric.Failed)
+		}
+		if response.SSE.Events != test.events || response.SSE.InvalidEvents != test.invalid {
+			t.Errorf("%s: got %d events, %d invalid, want %d, %d", test.name, response.SSE.Events, response.SSE.InvalidEvents, test.events, test.invalid)
+		}
+	}
+}
+
+func TestSSEInvalidExpectFailsWithoutSending(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	response, err := newTestClient(Options{}).ExecuteSSE(req, &types.SSEStream{Expect: "("})
+	if err == nil || response == nil || response.SSE == nil {
+		t.Fatalf("got %v, %v, want a failed response", response, err)
+	}
+	if !response.RequestMetric.Failed || !response.RequestMetric.NotSent || requests != 0 {
+		t.Errorf("unexpected metric %+v after %d requests", response.RequestMetric, requests)
+	}
+	if err := (&types.SSEStream{Expect: "("}).Validate(); err == nil {
+		t.Error("expected Validate to reject the pattern")
+	}
+}
+
+// endlessSSEServer sends an event every interval until the client leaves, or
+// only keep-alive comments when interval is 0.
+func endlessSSEServer(interval time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for {
+			if interval > 0 {
+				_, _ = w.Write([]byte("data: tick\n\n"))
+			} else {
+				_, _ = w.Write([]byte(": keep-alive\n\n"))
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(max(interval, 10*time.Millisecond)):
+			}
+		}
+	}))
+}
+
+func TestSSEDurationStopsTheStream(t *testing.T) {
+	server := endlessSSEServer(10 * time.Millisecond)
+	defer server.Close()
+
+	// The global timeout only bounds the first event, not the stream
+	client := newTestClient(Options{Timeout: 50 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	response, err := client.ExecuteSSE(req, &types.SSEStream{Duration: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.SSE.Events < 2 || response.SSE.StreamTime < 200*time.Millisecond || response.SSE.StreamTime > time.Second {
+		t.Errorf("got %d events in %s", response.SSE.Events, response.SSE.StreamTime)
+	}
+}
+
+func TestSSEWithoutEventsTimesOut(t *testing.T) {
+	server := endlessSSEServer(0)
+	defer server.Close()
+
+	client := newTestClient(Options{Timeout: 100 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	done := make(chan struct{})
+	var response *types.HTTPResponse
+	var err error
+	go func() {
+		defer close(done)
+		response, err = client.ExecuteSSE(req, &types.SSEStream{})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream was not cut by the timeout")
+	}
+	if err == nil || !strings.Contains(err.Error(), "no event received within 100ms") || !response.RequestMetric.Failed {
+		t.Errorf("got %v", err)
+	}
+}
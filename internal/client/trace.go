@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"goload/types"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type tracerKey struct{}
+
+// requestTracer times the connection phases of a request. Transport
+// callbacks may still fire after the response is returned, hence the mutex.
+type requestTracer struct {
+	mu           sync.Mutex
+	timing       types.RequestTiming
+	hopStart     time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	proxy        *url.URL
+	tunnelStart  time.Time
+	tunnelDone   bool
+	handshakes   int
+}
+
+// traceRequest returns req with a tracer attached to its context. Redirect
+// requests inherit the context, so every hop reports to the same tracer.
+func traceRequest(req *http.Request) (*http.Request, *requestTracer) {
+	tracer := &requestTracer{}
+	ctx := context.WithValue(req.Context(), tracerKey{}, tracer)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { tracer.at(func(now time.Time) { tracer.dnsStart = now }) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { tracer.at(tracer.dnsDone) },
+		ConnectStart:         func(string, string) { tracer.at(tracer.connectStarted) },
+		ConnectDone:          func(string, string, error) { tracer.at(tracer.connectDone) },
+		TLSHandshakeStart:    func() { tracer.at(tracer.tlsStarted) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tracer.at(tracer.tlsDone) },
+		GotConn:              func(info httptrace.GotConnInfo) { tracer.at(tracer.gotConn(info)) },
+		GotFirstResponseByte: func() { tracer.at(tracer.firstByte) },
+	})
+	return req.WithContext(ctx), tracer
+}
+
+// beginHop is called by do before each hop, with the proxy the hop goes
+// through, if any.
+func beginHop(req *http.Request, proxy *url.URL) {
+	tracer, ok := req.Context().Value(tracerKey{}).(*requestTracer)
+	if !ok {
+		return
+	}
+	tracer.at(func(now time.Time) {
+		tracer.hopStart = now
+		tracer.proxy = proxy
+		tracer.tunnelStart = time.Time{}
+		tracer.tunnelDone = false
+		tracer.handshakes = 0
+	})
+}
+
+func (t *requestTracer) at(event func(now time.Time)) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	event(now)
+}
+
+func (t *requestTracer) dnsDone(now time.Time) {
+	t.timing.DNS += now.Sub(t.dnsStart)
+}
+
+func (t *requestTracer) connectStarted(now time.Time) {
+	t.connectStart = now
+	if t.proxy != nil && t.tunnelStart.IsZero() {
+		t.tunnelStart = now
+	}
+}
+
+func (t *requestTracer) connectDone(now time.Time) {
+	t.timing.Connect += now.Sub(t.connectStart)
+}
+
+// tlsStarted ends the proxy tunnel when the handshake is the one with the
+// target, which follows the handshake with the proxy itself for https
+// proxies.
+func (t *requestTracer) tlsStarted(now time.Time) {
+	t.tlsStart = now
+	if t.proxy != nil && (t.proxy.Scheme != "https" || t.handshakes > 0) {
+		t.endTunnel(now)
+	}
+	t.handshakes++
+}
+
+func (t *requestTracer) tlsDone(now time.Time) {
+	t.timing.TLS += now.Sub(t.tlsStart)
+}
+
+func (t *requestTracer) gotConn(info httptrace.GotConnInfo) func(now time.Time) {
+	return func(now time.Time) {
+		if t.proxy != nil && !info.Reused {
+			t.endTunnel(now)
+		}
+	}
+}
+
+func (t *requestTracer) endTunnel(now time.Time) {
+	if t.tunnelDone || t.tunnelStart.IsZero() {
+		return
+	}
+	t.timing.ProxyConnect += now.Sub(t.tunnelStart)
+	t.tunnelDone = true
+}
+
+func (t *requestTracer) firstByte(now time.Time) {
+	t.timing.FirstByte = now.Sub(t.hopStart)
+}
+
+func (t *requestTracer) result() types.RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timing
+}
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"fmt"
+	"goload/types"
+	"net/http"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultExpectTimeout    = 10 * time.Second
+	defaultHandshakeTimeout = 45 * time.Second
+	closeTimeout            = time.Second
+)
+
+// handshakeHeaders are set by the websocket dialer itself.
+var handshakeHeaders = []string{
+	"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version",
+	"Sec-Websocket-Extensions", "Sec-Websocket-Protocol", "Accept-Encoding",
+}
+
+var expectPatterns sync.Map
+
+// ExecuteWebSocket opens a websocket connection to the URL of req, with its
+// headers, and plays scenario on it. The returned RequestMetric times the
+// handshake; the whole session is described by the WebSocket metric.
+func (c *Client) ExecuteWebSocket(req *http.Request, scenario *types.WebSocketScenario) (*types.HTTPResponse, error) {
+	metric := &types.WebSocketMetric{}
+	network := &types.NetworkMetric{}
+	response := &types.HTTPResponse{
+		FinalURL:      websocketURL(req),
+		RequestMetric: &types.RequestMetric{Protocol: "websocket"},
+		NetworkMetric: network,
+		WebSocket:     metric,
+	}
+	fail := func(err error) (*types.HTTPResponse, error) {
+		response.Error = err
+		response.RequestMetric.Failed = true
+		return response, err
+	}
+
+	handshakeTimeout := c.HttpClient.Timeout
+	if handshakeTimeout == 0 {
+		handshakeTimeout = defaultHandshakeTimeout
+	}
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: handshakeTimeout,
+		Jar:              c.HttpClient.Jar,
+		Subprotocols:     scenario.Subprotocols,
+	}
+	if transport, ok := c.HttpClient.Transport.(*http.Transport); ok {
+		dialer.Proxy = transport.Proxy
+		dialer.NetDialContext = transport.DialContext
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	header := req.Header.Clone()
+	for _, name := range handshakeHeaders {
+		header.Del(name)
+	}
+
+	startTime := time.Now()
+	conn, handshake, err := dialer.DialContext(req.Context(), response.FinalURL, header)
+	metric.ConnectTime = time.Since(startTime)
+	response.RequestMetric.Duration = metric.ConnectTime
+	if handshake != nil {
+		response.StatusCode = handshake.StatusCode
+		response.RequestMetric.StatusCode = handshake.StatusCode
+	}
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+
+	session := newWebSocketSession(conn)
+	err = session.play(scenario, metric, network)
+	if err == nil && scenario.Hold > 0 {
+		select {
+		case <-time.After(scenario.Hold):
+		case <-session.closed:
+		}
+	}
+	session.close()
+
+	metric.SessionTime = time.Since(startTime)
+	metric.MessagesReceived = session.received.Load()
+	network.BytesRecv = session.receivedBytes.Load()
+	network.BytesRecvDecoded = network.BytesRecv
+	metric.AbnormalClose = session.abnormalClose()
+	if err == nil && metric.AbnormalClose {
+		err = fmt.Errorf("connection closed abnormally: %s", session.readErr)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return response, nil
+}
+
+func websocketURL(req *http.Request) string {
+	target := *req.URL
+	switch target.Scheme {
+	case "http":
+		target.Scheme = "ws"
+	case "https":
+		target.Scheme = "wss"
+	}
+	return target.String()
+}
+
+type webSocketSession struct {
+	conn          *websocket.Conn
+	messages      chan []byte
+	closed        chan struct{}
+	readErr       error
+	closing       atomic.Bool
+	received      atomic.Int64
+	receivedBytes atomic.Int64
+}
+
+// newWebSocketSession starts reading the connection. Messages are queued for
+// the expect steps; when nobody consumes them they are only counted.
+func newWebSocketSession(conn *websocket.Conn) *webSocketSession {
+	session := &webSocketSession{
+		conn:     conn,
+		messages: make(chan []byte, 256),
+		closed:   make(chan struct{}),
+	}
+	go func() {
+		defer close(session.closed)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				session.readErr = err
+				return
+			}
+			session.received.Add(1)
+			session.receivedBytes.Add(int64(len(data)))
+			select {
+			case session.messages <- data:
+			default:
+			}
+		}
+	}()
+	return session
+}
+
+func (s *webSocketSession) play(scenario *types.WebSocketScenario, metric *types.WebSocketMetric, network *types.NetworkMetric) error {
+	var sentAt time.Time
+	for i, step := range scenario.Steps {
+		if step.Send != "" {
+			// Only the replies to this message can end its round trip
+			s.discardMessages()
+			messageType := websocket.TextMessage
+			if step.Binary {
+				messageType = websocket.BinaryMessage
+			}
+			if err := s.conn.WriteMessage(messageType, []byte(step.Send)); err != nil {
+				return fmt.Errorf("step %d: error sending message: %s", i+1, err)
+			}
+			sentAt = time.Now()
+			metric.MessagesSent++
+			network.BytesSent += int64(len(step.Send))
+		}
+
+		if step.Expect != "" {
+			pattern, err := expectPattern(step.Expect)
+			if err != nil {
+				return fmt.Errorf("step %d: %s", i+1, err)
+			}
+			if err := s.expect(pattern, step.Timeout); err != nil {
+				return fmt.Errorf("step %d: %s", i+1, err)
+			}
+			if !sentAt.IsZero() {
+				metric.RoundTrips = append(metric.RoundTrips, time.Since(sentAt))
+				sentAt = time.Time{}
+			}
+		}
+
+		if step.Wait > 0 {
+			select {
+			case <-time.After(step.Wait):
+			case <-s.closed:
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (s *webSocketSession) expect(pattern *regexp.Regexp, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultExpectTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case data := <-s.messages:
+			if pattern.Match(data) {
+				return nil
+			}
+		case <-s.closed:
+			// The reader is over, the messages it queued are all there
+			for {
+				select {
+				case data := <-s.messages:
+					if pattern.Match(data) {
+						return nil
+					}
+				default:
+					return fmt.Errorf("connection closed while expecting /%s/", pattern)
+				}
+			}
+		case <-timer.C:
+			return fmt.Errorf("no message matching /%s/ within %s", pattern, timeout)
+		}
+	}
+}
+
+// discardMessages drops the messages received and not expected yet.
+func (s *webSocketSession) discardMessages() {
+	for {
+		select {
+		case <-s.messages:
+		default:
+			return
+		}
+	}
+}
+
+func expectPattern(expect string) (*regexp.Regexp, error) {
+	if cached, ok := expectPatterns.Load(expect); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	pattern, err := regexp.Compile(expect)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expect pattern: %s", err)
+	}
+	expectPatterns.Store(expect, pattern)
+	return pattern, nil
+}
+
+// close performs the closing handshake, unless the peer already closed the
+// connection.
+func (s *webSocketSession) close() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	s.closing.Store(true)
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeTimeout)); err != nil {
+		return
+	}
+	select {
+	case <-s.closed:
+	case <-time.After(closeTimeout):
+	}
+}
+
+// abnormalClose tells whether the connection was lost or closed by the peer
+// with an error status, rather than closed normally.
+func (s *webSocketSession) abnormalClose() bool {
+	select {
+	case <-s.closed:
+	default:
+		return false
+	}
+	if websocket.IsCloseError(s.readErr, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return false
+	}
+	return !s.closing.Load()
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"goload/types"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func echoServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, append([]byte("echo "), message...)); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestWebSocketScenario(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	client := NewClient(Options{})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	response, err := client.ExecuteWebSocket(req, &types.WebSocketScenario{Steps: []types.WebSocketStep{
+		{Send: "ping", Expect: "^echo ping$"},
+		{Send: "pong", Expect: "^echo pong$"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metric := response.WebSocket
+	if metric.MessagesSent != 2 || metric.MessagesReceived != 2 || len(metric.RoundTrips) != 2 || metric.AbnormalClose {
+		t.Errorf("unexpected metric %+v", metric)
+	}
+	if response.RequestMetric.Protocol != "websocket" || response.RequestMetric.HasLatency() {
+		t.Errorf("the session must stay out of the request latency: %+v", response.RequestMetric)
+	}
+}
+
+func TestWebSocketExpectFails(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	client := NewClient(Options{})
+	for _, step := range []types.WebSocketStep{
+		{Send: "ping", Expect: "^pong$", Timeout: 100 * time.Millisecond},
+		{Send: "ping", Expect: "("},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		response, err := client.ExecuteWebSocket(req, &types.WebSocketScenario{Steps: []types.WebSocketStep{step}})
+		if err == nil || response == nil || !response.RequestMetric.Failed {
+			t.Errorf("expect %q: got %v, want a failed response", step.Expect, err)
+		}
+	}
+}
+
+func TestWebSocketExpectMatchesTheReplyBeforeTheClose(t *testing.T) {
+	pattern := regexp.MustCompile("^bye$")
+	for i := 0; i < 20; i++ {
+		// The peer replied, then closed the connection
+		session := &webSocketSession{messages: make(chan []byte, 2), closed: make(chan struct{})}
+		session.messages <- []byte("other")
+		session.messages <- []byte("bye")
+		close(session.closed)
+		if err := session.expect(pattern, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWebSocketSendDiscardsQueuedMessages(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("ready"))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	response, err := client.ExecuteWebSocket(req, &types.WebSocketScenario{Steps: []types.WebSocketStep{
+		{Wait: 50 * time.Millisecond},
+		{Send: "ping", Expect: "^ready$", Timeout: 100 * time.Millisecond},
+	}})
+	if err == nil {
+		t.Fatalf("the message received before the send ended its round trip: %+v", response.WebSocket)
+	}
+}
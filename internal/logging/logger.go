@@ -5,6 +5,7 @@ import (
 	"goload/types"
 	"goload/utils"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
@@ -57,17 +58,64 @@ func (logger *Logger) LogResponse(response types.HTTPResponse) error {
 
 	networkStats := ""
 	if response.NetworkMetric != nil {
-		networkStats = fmt.Sprintf("sent=%d bytes | recv=%d bytes",
+		networkStats = fmt.Sprintf("sent=%d bytes | recv=%d bytes (decoded=%d bytes)",
 			response.NetworkMetric.BytesSent,
-			response.NetworkMetric.BytesRecv)
+			response.NetworkMetric.BytesRecv,
+			response.NetworkMetric.BytesRecvDecoded)
 	}
 
-	logData := fmt.Sprintf("%s [executor-%06d] status=%d resp_time=%06dms | %s | %s\n",
+	extraStats := ""
+	if len(response.Hops) > 1 {
+		hops := make([]string, 0, len(response.Hops))
+		for _, hop := range response.Hops {
+			hops = append(hops, fmt.Sprintf("%d %s %dms", hop.StatusCode, hop.URL, hop.Duration.Milliseconds()))
+		}
+		extraStats = fmt.Sprintf(" | redirects=%d final_url=%s [%s]",
+			len(response.Hops)-1,
+			response.FinalURL,
+			strings.Join(hops, " -> "))
+	}
+
+	if response.RequestMetric != nil && response.RequestMetric.Timing.ProxyConnect > 0 {
+		extraStats += fmt.Sprintf(" | proxy_connect=%dms", response.RequestMetric.Timing.ProxyConnect.Milliseconds())
+	}
+	if response.WebSocket != nil {
+		extraStats += fmt.Sprintf(" | session=%dms sent=%d received=%d abnormal_close=%t",
+			response.WebSocket.SessionTime.Milliseconds(),
+			response.WebSocket.MessagesSent,
+			response.WebSocket.MessagesReceived,
+			response.WebSocket.AbnormalClose)
+	}
+	if response.GRPC != nil {
+		extraStats += fmt.Sprintf(" | grpc_code=%s sent=%d received=%d",
+			response.GRPC.Code,
+			response.GRPC.MessagesSent,
+			response.GRPC.MessagesReceived)
+	}
+	if response.SSE != nil {
+		extraStats += fmt.Sprintf(" | stream=%dms first_event=%dms events=%d invalid=%d",
+			response.SSE.StreamTime.Milliseconds(),
+			response.SSE.TimeToFirstEvent.Milliseconds(),
+			response.SSE.Events,
+			response.SSE.InvalidEvents)
+	}
+	if response.Socket != nil {
+		extraStats += fmt.Sprintf(" | %s connect=%dms response=%dms",
+			response.FinalURL,
+			response.Socket.ConnectTime.Milliseconds(),
+			response.Socket.ResponseTime.Milliseconds())
+	}
+	if response.Error != nil {
+		extraStats += fmt.Sprintf(" | error=%s", response.Error)
+	}
+
+	logData := fmt.Sprintf("%s [executor-%06d] status=%d resp_time=%06dms | %s%s | %s\n",
 		time.Now().Format(logger.Dateformat),
 		utils.GetGoroutineID(),
 		response.StatusCode,
 		response.RequestMetric.Duration.Milliseconds(),
 		networkStats,
+		extraStats,
 		response.Body)
 
 	_, err := logger.input.Write(logData)
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// csvHeader is the header of the CSV summary. Its columns are only ever
+// appended to, so spreadsheets can keep referring to them by position.
+var csvHeader = []string{
+	"group_by", "tags", "requests", "successes", "fails", "rps", "error_rate_pct",
+	"availability_pct", "min_ms", "mean_ms", "p50_ms", "p75_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms",
+}
+
+// WriteJSON writes the summary as indented JSON.
+func (s Summary) WriteJSON(output io.Writer) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV writes one row for the whole run, then one row per group of the
+// breakdowns. Tags are written as name=value pairs separated by semicolons.
+func (s Summary) WriteCSV(output io.Writer) error {
+	writer := csv.NewWriter(output)
+	_ = writer.Write(csvHeader)
+	_ = writer.Write(csvRow("", "", s.Requests, s.Latency))
+	for _, group := range s.Groups {
+		for _, row := range group.Rows {
+			pairs := make([]string, 0, len(row.Tags))
+			for _, name := range sortedKeys(row.Tags) {
+				pairs = append(pairs, name+"="+row.Tags[name])
+			}
+			_ = writer.Write(csvRow(strings.Join(group.By, ";"), strings.Join(pairs, ";"), row.Requests, row.Latency))
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRow(groupBy string, tags string, requests RequestSummary, latency LatencySummary) []string {
+	float := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 3, 64)
+	}
+	integer := func(value int64) string {
+		return strconv.FormatInt(value, 10)
+	}
+	return []string{
+		groupBy, tags, integer(requests.Total), integer(requests.Successes), integer(requests.Fails),
+		float(requests.RPS), float(requests.ErrorRatePct), float(requests.AvailabilityPct),
+		integer(latency.Min), float(latency.Mean), integer(latency.P50), integer(latency.P75),
+		integer(latency.P90), integer(latency.P95), integer(latency.P99), integer(latency.Max),
+	}
+}
+
+// ReadSummary reads a JSON summary written by WriteFile.
+func ReadSummary(path string) (Summary, error) {
+	var summary Summary
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return summary, fmt.Errorf("error reading summary %s: %s", path, err)
+	}
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return summary, fmt.Errorf("error parsing summary %s: %s", path, err)
+	}
+	if summary.SchemaVersion != SummarySchemaVersion {
+		return summary, fmt.Errorf("unsupported summary schema version %d", summary.SchemaVersion)
+	}
+	return summary, nil
+}
+
+// WriteFile writes the summary to path, as CSV for .csv files and JSON
+// otherwise.
+func (s Summary) WriteFile(path string) error {
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return s.WriteCSVFile(path)
+	}
+	return s.WriteJSONFile(path)
+}
+
+// WriteJSONFile writes the summary to path as JSON, whatever its extension.
+func (s Summary) WriteJSONFile(path string) error {
+	return writeFile(path, s.WriteJSON)
+}
+
+// WriteCSVFile writes the summary to path as CSV, whatever its extension.
+func (s Summary) WriteCSVFile(path string) error {
+	return writeFile(path, s.WriteCSV)
+}
+
+// WriteJUnitFile writes the summary to path as JUnit XML.
+func (s Summary) WriteJUnitFile(path string) error {
+	return writeFile(path, s.WriteJUnit)
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing summary %s: %s", path, err)
+	}
+	return nil
+}
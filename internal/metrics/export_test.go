@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSummary() Summary {
+	return Summary{
+		SchemaVersion: SummarySchemaVersion,
+		Collection:    "shop",
+		Passed:        true,
+		Requests:      RequestSummary{Total: 10, Successes: 9, Fails: 1, RPS: 2.5, ErrorRatePct: 10, AvailabilityPct: 90},
+		Latency:       LatencySummary{Min: 5, Mean: 12.5, P50: 10, P75: 14, P90: 20, P95: 25, P99: 30, Max: 31},
+		Groups: []GroupSummary{{
+			By: []string{"test", "phase"},
+			Rows: []GroupRow{{
+				Tags:     map[string]string{"test": "checkout", "phase": "peak"},
+				Requests: RequestSummary{Total: 10, Successes: 9, Fails: 1},
+				Latency:  LatencySummary{P50: 10},
+			}},
+		}},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var output bytes.Buffer
+	if err := testSummary().WriteCSV(&output); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"group_by,tags,requests,successes,fails,rps,error_rate_pct,availability_pct,min_ms,mean_ms,p50_ms,p75_ms,p90_ms,p95_ms,p99_ms,max_ms",
+		",,10,9,1,2.500,10.000,90.000,5,12.500,10,14,20,25,30,31",
+		"test;phase,phase=peak;test=checkout,10,9,1,0.000,0.000,0.000,0,0.000,10,0,0,0,0,0",
+	}, "\n") + "\n"
+	if output.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", output.String(), want)
+	}
+}
+
+func TestJSONSummaryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	summary := testSummary()
+	if err := summary.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSummary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, summary) {
+		t.Errorf("got %+v, want %+v", read, summary)
+	}
+
+	summary.SchemaVersion = SummarySchemaVersion + 1
+	if err := summary.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSummary(path); err == nil {
+		t.Error("expected an error for another schema version")
+	}
+}
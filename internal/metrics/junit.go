@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the summary as JUnit XML: a testsuite per test, with a
+// testcase per threshold and per check. A threshold fails its testcase when
+// it does not hold, and a check when any of its evaluations failed. A test
+// with neither gets a requests testcase, failing when any request failed.
+func (s Summary) WriteJUnit(output io.Writer) error {
+	suites := junitTestSuites{Name: s.Collection, Time: seconds(s.DurationSecs)}
+	for _, test := range s.Tests {
+		suite := junitTestSuite{
+			Name:      test.Name,
+			Time:      seconds(test.EndedAt.Sub(test.StartedAt).Seconds()),
+			Timestamp: test.StartedAt.Format("2006-01-02T15:04:05"),
+			SystemOut: fmt.Sprintf("requests: %d, rps: %.2f, error rate: %.2f%%, p95: %d ms, p99: %d ms",
+				test.Requests.Total, test.Requests.RPS, test.Requests.ErrorRatePct, test.Latency.P95, test.Latency.P99),
+		}
+		className := test.Name
+		if s.Collection != "" {
+			className = s.Collection + "." + test.Name
+		}
+		for _, threshold := range s.Thresholds {
+			if threshold.Test != test.Name {
+				continue
+			}
+			testCase := junitTestCase{
+				ClassName: className,
+				Name:      fmt.Sprintf("%s %s %s", threshold.Kind, threshold.Metric, threshold.Target),
+				Time:      "0",
+			}
+			if !threshold.Passed {
+				message := fmt.Sprintf("%s is %.2f, expected %s", threshold.Metric, threshold.Value, threshold.Target)
+				if threshold.Kind == "fail_if" {
+					message = fmt.Sprintf("%s is %.2f, which meets the fail_if target %s", threshold.Metric, threshold.Value, threshold.Target)
+				}
+				if threshold.Error != "" {
+					message = fmt.Sprintf("%s: %s", threshold.Metric, threshold.Error)
+				}
+				testCase.Failure = &junitFailure{Message: message, Type: "threshold", Text: message}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		for _, check := range test.CheckResults {
+			testCase := junitTestCase{ClassName: className, Name: "check " + check.Id, Time: "0"}
+			if check.Fails > 0 {
+				total := check.Passes + check.Fails
+				message := fmt.Sprintf("%s failed %d of %d times, passed %.2f%% instead of 100%%",
+					check.Id, check.Fails, total, float64(check.Passes)/float64(total)*100)
+				testCase.Failure = &junitFailure{Message: message, Type: "check", Text: message}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		if len(suite.Cases) == 0 {
+			// Without thresholds nor checks, the failed requests decide
+			testCase := junitTestCase{ClassName: className, Name: "requests", Time: suite.Time}
+			if test.Requests.ErrorRatePct > 0 {
+				message := fmt.Sprintf("%d of %d requests failed, error rate %.2f%%",
+					test.Requests.Fails, test.Requests.Total, test.Requests.ErrorRatePct)
+				testCase.Failure = &junitFailure{Message: message, Type: "requests", Text: message}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		for _, testCase := range suite.Cases {
+			suite.Tests++
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(output, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(output)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(output, "\n")
+	return err
+}
+
+func seconds(value float64) string {
+	return strconv.FormatFloat(value, 'f', 3, 64)
+}
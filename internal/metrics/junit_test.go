@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	summary := Summary{
+		Collection:   "shop",
+		DurationSecs: 90,
+		Tests: []TestSummary{
+			{
+				Name: "checkout",
+				TestStats: TestStats{
+					Requests:  RequestSummary{Total: 400, Successes: 388, Fails: 12, ErrorRatePct: 3},
+					StartedAt: started,
+					EndedAt:   started.Add(time.Minute),
+				},
+				CheckResults: []CheckSummary{{Id: "status_code == 200", Passes: 388, Fails: 12}},
+			},
+			{
+				Name:      "browse",
+				TestStats: TestStats{Requests: RequestSummary{Total: 100, Successes: 100}, StartedAt: started, EndedAt: started.Add(30 * time.Second)},
+			},
+			{
+				Name:      "search",
+				TestStats: TestStats{Requests: RequestSummary{Total: 50, Successes: 49, Fails: 1, ErrorRatePct: 2}, StartedAt: started, EndedAt: started},
+			},
+		},
+		Thresholds: []ThresholdResult{
+			{Test: "checkout", Kind: "pass_if", Metric: "latency_ms.p95", Target: "<=200", Value: 250},
+			{Test: "checkout", Kind: "fail_if", Metric: "error_rate_pct", Target: ">5", Value: 3, Passed: true},
+		},
+	}
+	var output bytes.Buffer
+	if err := summary.WriteJUnit(&output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(output.String(), xml.Header) {
+		t.Error("missing the XML header")
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(output.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Name != "shop" || suites.Tests != 5 || suites.Failures != 3 || suites.Time != "90.000" {
+		t.Errorf("got suites %s with %d tests, %d failures in %s", suites.Name, suites.Tests, suites.Failures, suites.Time)
+	}
+	if len(suites.Suites) != 3 {
+		t.Fatalf("got %d suites", len(suites.Suites))
+	}
+
+	checkout := suites.Suites[0]
+	if checkout.Tests != 3 || checkout.Failures != 2 || checkout.Time != "60.000" || checkout.Timestamp != "2024-05-01T12:00:00" {
+		t.Errorf("got %+v", checkout)
+	}
+	for i, want := range []struct {
+		name, failure string
+	}{
+		{"pass_if latency_ms.p95 <=200", "latency_ms.p95 is 250.00, expected <=200"},
+		{"fail_if error_rate_pct >5", ""},
+		{"check status_code == 200", "status_code == 200 failed 12 of 400 times, passed 97.00% instead of 100%"},
+	} {
+		testCase := checkout.Cases[i]
+		if testCase.Name != want.name || testCase.ClassName != "shop.checkout" {
+			t.Errorf("got case %s of %s", testCase.Name, testCase.ClassName)
+		}
+		if (testCase.Failure == nil) != (want.failure == "") || (testCase.Failure != nil && testCase.Failure.Message != want.failure) {
+			t.Errorf("%s: got failure %+v, want %q", want.name, testCase.Failure, want.failure)
+		}
+	}
+
+	// Without thresholds nor checks, a requests testcase judges the test
+	browse, search := suites.Suites[1], suites.Suites[2]
+	if len(browse.Cases) != 1 || browse.Cases[0].Name != "requests" || browse.Cases[0].Failure != nil || browse.Cases[0].Time != "30.000" {
+		t.Errorf("got browse cases %+v", browse.Cases)
+	}
+	if !strings.Contains(browse.SystemOut, "requests: 100") {
+		t.Errorf("got system-out %q", browse.SystemOut)
+	}
+	if len(search.Cases) != 1 || search.Failures != 1 || search.Cases[0].Failure == nil ||
+		search.Cases[0].Failure.Message != "1 of 50 requests failed, error rate 2.00%" {
+		t.Errorf("got search cases %+v", search.Cases)
+	}
+}
@@ -6,20 +6,102 @@ import (
 	"goload/internal/logging"
 	"goload/internal/worker"
 	"goload/types"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type MetricsCollector struct {
 	Logger                       logging.Logger
+	GroupBy                      [][]string // Tag combinations the stats are broken down by
 	requestLatencyHistogramMutex *sync.Mutex
 	requestLatencyHistogram      *hdrhistogram.Histogram
-	totalChacks                  int64
+	totalChecks                  int64
+	totalCheckFails              int64
+	checks                       map[string]*CheckStats
+	testChecks                   map[string]map[string]*CheckStats // Per test, then per check
+	graphQLOperations            map[string]*GraphQLStats
+	webSocketConnectHistogram    *hdrhistogram.Histogram
+	webSocketRoundTripHistogram  *hdrhistogram.Histogram
+	webSocketStats               WebSocketStats
+	grpcMethods                  map[string]*GRPCStats
+	sseFirstEventHistogram       *hdrhistogram.Histogram
+	sseGapHistogram              *hdrhistogram.Histogram
+	sseStats                     SSEStats
+	sockets                      map[string]*SocketStats
+	timingHistograms             map[string]*hdrhistogram.Histogram
+	rateLimits                   map[string]*RateLimitStats
+	series                       map[string]*TagGroup
+	errors                       map[string]int64
+	timeseries                   *timeseries
+	activeVUs                    atomic.Int64
+	inFlightRequests             atomic.Int64
+	outputs                      []Output
 	totalRequests                int64
 	totalFails                   int64
 	totalSuccesses               int64
+	authLatencyHistogram         *hdrhistogram.Histogram
+	totalAuthFetches             int64
+	totalAuthFails               int64
+	totalBytesSent               int64
+	totalBytesRecv               int64
+	totalBytesRecvDecoded        int64
 	MetricWorkerPool             *worker.WorkerPool[MetricWorkerTask]
 }
 
+type CheckStats struct {
+	Passes int64
+	Fails  int64
+}
+
+type GraphQLStats struct {
+	Requests       int64
+	FailedRequests int64
+	Errors         int64
+}
+
+type WebSocketStats struct {
+	Sessions         int64
+	MessagesSent     int64
+	MessagesReceived int64
+	AbnormalCloses   int64
+}
+
+type GRPCStats struct {
+	Calls            int64
+	Codes            map[string]int64
+	MessagesSent     int64
+	MessagesReceived int64
+}
+
+type SSEStats struct {
+	Streams       int64
+	Events        int64
+	InvalidEvents int64
+}
+
+type SocketStats struct {
+	Exchanges             int64
+	Timeouts              int64
+	ConnectHistogram      *hdrhistogram.Histogram
+	ResponseTimeHistogram *hdrhistogram.Histogram
+}
+
+type RateLimitStats struct {
+	Requests      int64
+	Throttled     int64
+	WaitHistogram *hdrhistogram.Histogram
+}
+
+// maxErrors bounds the distinct errors counted, the others are counted
+// together.
+const maxErrors = 100
+
+// timingPhases are the phases of RequestTiming, in the order they are printed.
+var timingPhases = []string{"DNS", "Connect", "TLS", "Proxy Connect", "First Byte"}
+
 type MetricWorkerTask struct {
 	TaskType string
 	TaskData interface{}
@@ -27,7 +109,24 @@ type MetricWorkerTask struct {
 
 func (collector *MetricsCollector) Init() error {
 	collector.requestLatencyHistogram = hdrhistogram.New(1, 60_000_000, 3)
+	collector.authLatencyHistogram = hdrhistogram.New(1, 60_000_000, 3)
+	collector.webSocketConnectHistogram = hdrhistogram.New(1, 60_000_000, 3)
+	collector.webSocketRoundTripHistogram = hdrhistogram.New(1, 60_000_000, 3)
+	collector.sseFirstEventHistogram = hdrhistogram.New(1, 60_000_000, 3)
+	collector.sseGapHistogram = hdrhistogram.New(1, 60_000_000, 3)
 	collector.requestLatencyHistogramMutex = &sync.Mutex{}
+	collector.checks = make(map[string]*CheckStats)
+	collector.testChecks = make(map[string]map[string]*CheckStats)
+	collector.graphQLOperations = make(map[string]*GraphQLStats)
+	collector.grpcMethods = make(map[string]*GRPCStats)
+	collector.sockets = make(map[string]*SocketStats)
+	collector.timingHistograms = make(map[string]*hdrhistogram.Histogram)
+	collector.rateLimits = make(map[string]*RateLimitStats)
+	collector.series = make(map[string]*TagGroup)
+	collector.errors = make(map[string]int64)
+	for _, phase := range timingPhases {
+		collector.timingHistograms[phase] = hdrhistogram.New(1, 60_000_000, 3)
+	}
 	collector.MetricWorkerPool = worker.NewWorkerPool[MetricWorkerTask](10, func(task MetricWorkerTask) {
 		err := collector.metricWorkerHandler(task)
 		if err != nil {
@@ -41,27 +140,194 @@ func (collector *MetricsCollector) metricWorkerHandler(task MetricWorkerTask) er
 	if task.TaskType == "request" {
 		requestMetric := task.TaskData.(types.RequestMetric)
 		collector.requestLatencyHistogramMutex.Lock()
-		err := collector.requestLatencyHistogram.RecordValue(requestMetric.Duration.Milliseconds())
+		var err error
+		if requestMetric.HasLatency() {
+			err = collector.requestLatencyHistogram.RecordValue(requestMetric.Duration.Milliseconds())
+			collector.recordTiming(requestMetric.Timing)
+		}
+		collector.recordSeries(requestMetric)
+		if collector.timeseries != nil {
+			collector.timeseries.record(requestMetric, collector.activeVUs.Load())
+		}
 		collector.totalRequests++
-		if requestMetric.StatusCode >= 200 && requestMetric.StatusCode < 300 {
+		if requestMetric.Succeeded() {
 			collector.totalSuccesses++
 		} else {
 			collector.totalFails++
+			collector.recordError(requestMetric)
 		}
 		collector.requestLatencyHistogramMutex.Unlock()
 		if err != nil {
 			_ = fmt.Errorf("error recording request latency: %s", err)
 		}
+	} else if task.TaskType == "auth" {
+		authMetric := task.TaskData.(types.RequestMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		err := collector.authLatencyHistogram.RecordValue(authMetric.Duration.Milliseconds())
+		collector.totalAuthFetches++
+		if authMetric.Failed {
+			collector.totalAuthFails++
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
+		if err != nil {
+			_ = fmt.Errorf("error recording auth latency: %s", err)
+		}
+	} else if task.TaskType == "graphql" {
+		graphQLMetric := task.TaskData.(types.GraphQLMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		stats, ok := collector.graphQLOperations[graphQLMetric.OperationName]
+		if !ok {
+			stats = &GraphQLStats{}
+			collector.graphQLOperations[graphQLMetric.OperationName] = stats
+		}
+		stats.Requests++
+		stats.Errors += int64(graphQLMetric.Errors)
+		if graphQLMetric.Failed {
+			stats.FailedRequests++
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
+	} else if task.TaskType == "websocket" {
+		webSocketMetric := task.TaskData.(types.WebSocketMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		_ = collector.webSocketConnectHistogram.RecordValue(webSocketMetric.ConnectTime.Milliseconds())
+		for _, roundTrip := range webSocketMetric.RoundTrips {
+			_ = collector.webSocketRoundTripHistogram.RecordValue(roundTrip.Milliseconds())
+		}
+		collector.webSocketStats.Sessions++
+		collector.webSocketStats.MessagesSent += webSocketMetric.MessagesSent
+		collector.webSocketStats.MessagesReceived += webSocketMetric.MessagesReceived
+		if webSocketMetric.AbnormalClose {
+			collector.webSocketStats.AbnormalCloses++
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
+	} else if task.TaskType == "grpc" {
+		grpcMetric := task.TaskData.(types.GRPCMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		stats, ok := collector.grpcMethods[grpcMetric.Method]
+		if !ok {
+			stats = &GRPCStats{Codes: make(map[string]int64)}
+			collector.grpcMethods[grpcMetric.Method] = stats
+		}
+		stats.Calls++
+		stats.Codes[grpcMetric.Code]++
+		stats.MessagesSent += grpcMetric.MessagesSent
+		stats.MessagesReceived += grpcMetric.MessagesReceived
+		collector.requestLatencyHistogramMutex.Unlock()
+	} else if task.TaskType == "sse" {
+		sseMetric := task.TaskData.(types.SSEMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		if sseMetric.Events > 0 {
+			_ = collector.sseFirstEventHistogram.RecordValue(sseMetric.TimeToFirstEvent.Milliseconds())
+		}
+		for _, gap := range sseMetric.Gaps {
+			_ = collector.sseGapHistogram.RecordValue(gap.Milliseconds())
+		}
+		collector.sseStats.Streams++
+		collector.sseStats.Events += sseMetric.Events
+		collector.sseStats.InvalidEvents += sseMetric.InvalidEvents
+		collector.requestLatencyHistogramMutex.Unlock()
+	} else if task.TaskType == "socket" {
+		socketMetric := task.TaskData.(types.SocketMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		stats, ok := collector.sockets[socketMetric.Protocol]
+		if !ok {
+			stats = &SocketStats{
+				ConnectHistogram:      hdrhistogram.New(1, 60_000_000, 3),
+				ResponseTimeHistogram: hdrhistogram.New(1, 60_000_000, 3),
+			}
+			collector.sockets[socketMetric.Protocol] = stats
+		}
+		stats.Exchanges++
+		if socketMetric.TimedOut {
+			stats.Timeouts++
+		}
+		if socketMetric.Connected {
+			_ = stats.ConnectHistogram.RecordValue(socketMetric.ConnectTime.Milliseconds())
+		}
+		// A timed out read lasts the read timeout, not the server
+		if socketMetric.AwaitedReply && !socketMetric.TimedOut {
+			_ = stats.ResponseTimeHistogram.RecordValue(socketMetric.ResponseTime.Milliseconds())
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
+	} else if task.TaskType == "ratelimit" {
+		rateLimitMetric := task.TaskData.(types.RateLimitMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		stats, ok := collector.rateLimits[rateLimitMetric.Key]
+		if !ok {
+			stats = &RateLimitStats{WaitHistogram: hdrhistogram.New(1, 60_000_000, 3)}
+			collector.rateLimits[rateLimitMetric.Key] = stats
+		}
+		stats.Requests++
+		if rateLimitMetric.Throttled {
+			stats.Throttled++
+			_ = stats.WaitHistogram.RecordValue(rateLimitMetric.Wait.Milliseconds())
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
 	} else if task.TaskType == "network" {
-		//
+		networkMetric := task.TaskData.(types.NetworkMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		collector.totalBytesSent += networkMetric.BytesSent
+		collector.totalBytesRecv += networkMetric.BytesRecv
+		collector.totalBytesRecvDecoded += networkMetric.BytesRecvDecoded
+		collector.requestLatencyHistogramMutex.Unlock()
 	} else if task.TaskType == "check" {
-		// Define task
+		checkMetric := task.TaskData.(types.CheckMetric)
+		collector.requestLatencyHistogramMutex.Lock()
+		stats, ok := collector.checks[checkMetric.Id]
+		if !ok {
+			stats = &CheckStats{}
+			collector.checks[checkMetric.Id] = stats
+		}
+		testChecks, ok := collector.testChecks[checkMetric.Test]
+		if !ok {
+			testChecks = make(map[string]*CheckStats)
+			collector.testChecks[checkMetric.Test] = testChecks
+		}
+		testStats, ok := testChecks[checkMetric.Id]
+		if !ok {
+			testStats = &CheckStats{}
+			testChecks[checkMetric.Id] = testStats
+		}
+		collector.totalChecks++
+		if checkMetric.Passed {
+			stats.Passes++
+			testStats.Passes++
+		} else {
+			stats.Fails++
+			testStats.Fails++
+			collector.totalCheckFails++
+		}
+		collector.requestLatencyHistogramMutex.Unlock()
 	} else {
 		return fmt.Errorf("unknown task type: %s", task.TaskType)
 	}
 	return nil
 }
 
+// recordTiming records the phases a request went through. The phases skipped
+// by reused connections are left out of their percentiles.
+func (collector *MetricsCollector) recordTiming(timing types.RequestTiming) {
+	durations := []time.Duration{timing.DNS, timing.Connect, timing.TLS, timing.ProxyConnect, timing.FirstByte}
+	for i, duration := range durations {
+		if duration > 0 {
+			_ = collector.timingHistograms[timingPhases[i]].RecordValue(duration.Microseconds())
+		}
+	}
+}
+
+// recordError counts a failed request by its error, or its status code when
+// it got a response.
+func (collector *MetricsCollector) recordError(metric types.RequestMetric) {
+	key := metric.Error
+	if key == "" {
+		key = fmt.Sprintf("status %d", metric.StatusCode)
+	}
+	if _, ok := collector.errors[key]; !ok && len(collector.errors) >= maxErrors {
+		key = "other errors"
+	}
+	collector.errors[key]++
+}
+
 func (collector *MetricsCollector) PrintRequestLatencyPercentiles(percentile float32) {
 	fmt.Printf("Request Latency Percentiles at %03.1f %% : %f\n", percentile, float64(collector.requestLatencyHistogram.ValueAtQuantile(float64(percentile))))
 }
@@ -84,7 +350,79 @@ func (collector *MetricsCollector) IngestNetworkMetric(metric types.NetworkMetri
 	return nil
 }
 
+// IngestAuthMetric records a token fetch. Token fetches are kept apart from
+// the test traffic so they do not skew the request latency percentiles.
+func (collector *MetricsCollector) IngestAuthMetric(metric types.RequestMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "auth",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+func (collector *MetricsCollector) IngestGraphQLMetric(metric types.GraphQLMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "graphql",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+func (collector *MetricsCollector) IngestWebSocketMetric(metric types.WebSocketMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "websocket",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+func (collector *MetricsCollector) IngestGRPCMetric(metric types.GRPCMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "grpc",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+func (collector *MetricsCollector) IngestSSEMetric(metric types.SSEMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "sse",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+func (collector *MetricsCollector) IngestSocketMetric(metric types.SocketMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "socket",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
+// IngestRateLimitMetric records the wait of a request for its rate limit,
+// kept apart from the request latency.
+func (collector *MetricsCollector) IngestRateLimitMetric(metric types.RateLimitMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "ratelimit",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
+	return nil
+}
+
 func (collector *MetricsCollector) IngestCheckMetric(metric types.CheckMetric) error {
+	metricTask := MetricWorkerTask{
+		TaskType: "check",
+		TaskData: metric,
+	}
+	collector.MetricWorkerPool.AddTask(metricTask)
 	return nil
 }
 
@@ -98,6 +436,9 @@ func (collector *MetricsCollector) LogRequestsStats() {
 	table += fmt.Sprintf("| Total Requests  | %-9d |\n", collector.totalRequests)
 	table += fmt.Sprintf("| Total Successes | %-9d |\n", collector.totalSuccesses)
 	table += fmt.Sprintf("| Total Fails	 | %-9d |\n", collector.totalFails)
+	table += fmt.Sprintf("| Bytes Sent      | %-9d |\n", collector.totalBytesSent)
+	table += fmt.Sprintf("| Bytes Recv      | %-9d |\n", collector.totalBytesRecv)
+	table += fmt.Sprintf("| Bytes Decoded   | %-9d |\n", collector.totalBytesRecvDecoded)
 	table += fmt.Sprintf("+-----------------+-----------+\n")
 	table += fmt.Sprintf("\nLatency Percentiles (ms):\n")
 	table += fmt.Sprintf("+------------+-----------+\n")
@@ -110,6 +451,156 @@ func (collector *MetricsCollector) LogRequestsStats() {
 	}
 	table += fmt.Sprintf("+------------+-----------+\n")
 
+	timingTable := ""
+	for _, phase := range timingPhases {
+		histogram := collector.timingHistograms[phase]
+		if histogram.TotalCount() == 0 {
+			continue
+		}
+		timingTable += fmt.Sprintf("| %-13s | %-9d | %-9.1f | %-9.1f |\n", phase, histogram.TotalCount(),
+			float64(histogram.ValueAtQuantile(50))/1000, float64(histogram.ValueAtQuantile(95))/1000)
+	}
+	if timingTable != "" {
+		table += fmt.Sprintf("\nTiming Breakdown (ms):\n")
+		table += fmt.Sprintf("+---------------+-----------+-----------+-----------+\n")
+		table += fmt.Sprintf("| Phase         | Count     | p50       | p95       |\n")
+		table += fmt.Sprintf("+---------------+-----------+-----------+-----------+\n")
+		table += timingTable
+		table += fmt.Sprintf("+---------------+-----------+-----------+-----------+\n")
+	}
+
+	for _, names := range collector.GroupBy {
+		table += collector.groupTable(names)
+	}
+
+	if collector.timeseries != nil {
+		table += collector.timeseries.table()
+	}
+
+	if collector.totalChecks > 0 {
+		ids := make([]string, 0, len(collector.checks))
+		for id := range collector.checks {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		table += fmt.Sprintf("\nChecks (%d/%d passed):\n", collector.totalChecks-collector.totalCheckFails, collector.totalChecks)
+		for _, id := range ids {
+			stats := collector.checks[id]
+			table += fmt.Sprintf("  %-40s passes=%-9d fails=%d\n", id, stats.Passes, stats.Fails)
+		}
+	}
+
+	if len(collector.graphQLOperations) > 0 {
+		operations := make([]string, 0, len(collector.graphQLOperations))
+		for operation := range collector.graphQLOperations {
+			operations = append(operations, operation)
+		}
+		sort.Strings(operations)
+		table += fmt.Sprintf("\nGraphQL Operations:\n")
+		for _, operation := range operations {
+			stats := collector.graphQLOperations[operation]
+			name := operation
+			if name == "" {
+				name = "(anonymous)"
+			}
+			table += fmt.Sprintf("  %-40s requests=%-9d failed=%-9d errors=%d\n", name, stats.Requests, stats.FailedRequests, stats.Errors)
+		}
+	}
+
+	if collector.webSocketStats.Sessions > 0 {
+		table += fmt.Sprintf("\nWebSocket Sessions:\n")
+		table += fmt.Sprintf("+-------------------+-----------+\n")
+		table += fmt.Sprintf("| Sessions          | %-9d |\n", collector.webSocketStats.Sessions)
+		table += fmt.Sprintf("| Messages Sent     | %-9d |\n", collector.webSocketStats.MessagesSent)
+		table += fmt.Sprintf("| Messages Received | %-9d |\n", collector.webSocketStats.MessagesReceived)
+		table += fmt.Sprintf("| Abnormal Closes   | %-9d |\n", collector.webSocketStats.AbnormalCloses)
+		table += fmt.Sprintf("| Connect p95 ms    | %-9d |\n", collector.webSocketConnectHistogram.ValueAtQuantile(95))
+		table += fmt.Sprintf("| Round Trip p50 ms | %-9d |\n", collector.webSocketRoundTripHistogram.ValueAtQuantile(50))
+		table += fmt.Sprintf("| Round Trip p95 ms | %-9d |\n", collector.webSocketRoundTripHistogram.ValueAtQuantile(95))
+		table += fmt.Sprintf("+-------------------+-----------+\n")
+	}
+
+	if len(collector.grpcMethods) > 0 {
+		methods := make([]string, 0, len(collector.grpcMethods))
+		for method := range collector.grpcMethods {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		table += fmt.Sprintf("\ngRPC Methods:\n")
+		for _, method := range methods {
+			stats := collector.grpcMethods[method]
+			codeNames := make([]string, 0, len(stats.Codes))
+			for code := range stats.Codes {
+				codeNames = append(codeNames, code)
+			}
+			sort.Strings(codeNames)
+			codes := make([]string, 0, len(codeNames))
+			for _, code := range codeNames {
+				codes = append(codes, fmt.Sprintf("%s=%d", code, stats.Codes[code]))
+			}
+			table += fmt.Sprintf("  %-40s calls=%-9d sent=%-9d received=%-9d %s\n",
+				method, stats.Calls, stats.MessagesSent, stats.MessagesReceived, strings.Join(codes, " "))
+		}
+	}
+
+	if collector.sseStats.Streams > 0 {
+		table += fmt.Sprintf("\nSSE Streams:\n")
+		table += fmt.Sprintf("+--------------------+-----------+\n")
+		table += fmt.Sprintf("| Streams            | %-9d |\n", collector.sseStats.Streams)
+		table += fmt.Sprintf("| Events             | %-9d |\n", collector.sseStats.Events)
+		table += fmt.Sprintf("| Invalid Events     | %-9d |\n", collector.sseStats.InvalidEvents)
+		table += fmt.Sprintf("| First Event p50 ms | %-9d |\n", collector.sseFirstEventHistogram.ValueAtQuantile(50))
+		table += fmt.Sprintf("| First Event p95 ms | %-9d |\n", collector.sseFirstEventHistogram.ValueAtQuantile(95))
+		table += fmt.Sprintf("| Event Gap p50 ms   | %-9d |\n", collector.sseGapHistogram.ValueAtQuantile(50))
+		table += fmt.Sprintf("| Event Gap p95 ms   | %-9d |\n", collector.sseGapHistogram.ValueAtQuantile(95))
+		table += fmt.Sprintf("+--------------------+-----------+\n")
+	}
+
+	if len(collector.sockets) > 0 {
+		protocols := make([]string, 0, len(collector.sockets))
+		for protocol := range collector.sockets {
+			protocols = append(protocols, protocol)
+		}
+		sort.Strings(protocols)
+		table += fmt.Sprintf("\nSocket Exchanges:\n")
+		for _, protocol := range protocols {
+			stats := collector.sockets[protocol]
+			table += fmt.Sprintf("  %-5s exchanges=%-9d timeouts=%-9d connect_p95=%dms response_p50=%dms response_p95=%dms\n",
+				protocol, stats.Exchanges, stats.Timeouts,
+				stats.ConnectHistogram.ValueAtQuantile(95),
+				stats.ResponseTimeHistogram.ValueAtQuantile(50),
+				stats.ResponseTimeHistogram.ValueAtQuantile(95))
+		}
+	}
+
+	if len(collector.rateLimits) > 0 {
+		keys := make([]string, 0, len(collector.rateLimits))
+		for key := range collector.rateLimits {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		table += fmt.Sprintf("\nRate Limits:\n")
+		for _, key := range keys {
+			stats := collector.rateLimits[key]
+			table += fmt.Sprintf("  %-40s requests=%-9d throttled=%-9d wait_p50=%dms wait_p95=%dms wait_max=%dms\n",
+				key, stats.Requests, stats.Throttled,
+				stats.WaitHistogram.ValueAtQuantile(50),
+				stats.WaitHistogram.ValueAtQuantile(95),
+				stats.WaitHistogram.Max())
+		}
+	}
+
+	if collector.totalAuthFetches > 0 {
+		table += fmt.Sprintf("\nToken Fetches:\n")
+		table += fmt.Sprintf("+-----------------+-----------+\n")
+		table += fmt.Sprintf("| Total Fetches   | %-9d |\n", collector.totalAuthFetches)
+		table += fmt.Sprintf("| Total Fails     | %-9d |\n", collector.totalAuthFails)
+		table += fmt.Sprintf("| Latency p50 ms  | %-9d |\n", collector.authLatencyHistogram.ValueAtQuantile(50))
+		table += fmt.Sprintf("| Latency p95 ms  | %-9d |\n", collector.authLatencyHistogram.ValueAtQuantile(95))
+		table += fmt.Sprintf("| Latency max ms  | %-9d |\n", collector.authLatencyHistogram.Max())
+		table += fmt.Sprintf("+-----------------+-----------+\n")
+	}
+
 	collector.Logger.LogWithoutDate(table)
 }
 
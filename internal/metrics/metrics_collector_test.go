@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"goload/types"
+	"testing"
+	"time"
+)
+
+func TestRequestsWithoutLatency(t *testing.T) {
+	collector := &MetricsCollector{}
+	if err := collector.Init(); err != nil {
+		t.Fatal(err)
+	}
+	tags := map[string]string{"test": "checkout"}
+	for _, metric := range []types.RequestMetric{
+		{Duration: 120 * time.Millisecond, StatusCode: 200, Tags: tags},
+		{Failed: true, NotSent: true, Tags: tags},
+		{Duration: 30 * time.Second, Protocol: "websocket", Tags: tags},
+	} {
+		if err := collector.metricWorkerHandler(MetricWorkerTask{TaskType: "request", TaskData: metric}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if collector.totalRequests != 3 || collector.totalFails != 1 {
+		t.Errorf("got %d requests and %d failures, want 3 and 1", collector.totalRequests, collector.totalFails)
+	}
+	if count := collector.requestLatencyHistogram.TotalCount(); count != 1 {
+		t.Errorf("latency histogram has %d values, want 1", count)
+	}
+	groups := collector.Group([]string{"test"})
+	if len(groups) != 1 || groups[0].Requests != 3 || groups[0].Histogram.TotalCount() != 1 {
+		t.Errorf("unexpected tag groups %+v", groups)
+	}
+}
+
+func TestGraphQLFailedRequests(t *testing.T) {
+	collector := &MetricsCollector{}
+	if err := collector.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range []types.GraphQLMetric{
+		{OperationName: "Cart"},
+		{OperationName: "Cart", Errors: 2, Failed: true},
+		// Not a GraphQL response: no errors, but failed
+		{OperationName: "Cart", Failed: true},
+	} {
+		if err := collector.metricWorkerHandler(MetricWorkerTask{TaskType: "graphql", TaskData: metric}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := collector.graphQLOperations["Cart"]
+	if stats.Requests != 3 || stats.FailedRequests != 2 || stats.Errors != 2 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestSocketTimings(t *testing.T) {
+	collector := &MetricsCollector{}
+	if err := collector.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range []types.SocketMetric{
+		{Protocol: "tcp", ConnectTime: 5 * time.Millisecond, Connected: true, ResponseTime: 20 * time.Millisecond, AwaitedReply: true},
+		// Refused connection
+		{Protocol: "tcp", ConnectTime: 900 * time.Millisecond},
+		{Protocol: "tcp", ConnectTime: 5 * time.Millisecond, Connected: true, ResponseTime: 10 * time.Second, AwaitedReply: true, TimedOut: true},
+		{Protocol: "udp", ResponseTime: 3 * time.Millisecond, AwaitedReply: true},
+		// await_reply: false
+		{Protocol: "udp"},
+	} {
+		if err := collector.metricWorkerHandler(MetricWorkerTask{TaskType: "socket", TaskData: metric}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tcp, udp := collector.sockets["tcp"], collector.sockets["udp"]
+	if tcp.Exchanges != 3 || tcp.Timeouts != 1 || tcp.ConnectHistogram.TotalCount() != 2 || tcp.ConnectHistogram.Max() != 5 {
+		t.Errorf("tcp: %d exchanges, %d timeouts, %d connects up to %dms", tcp.Exchanges, tcp.Timeouts, tcp.ConnectHistogram.TotalCount(), tcp.ConnectHistogram.Max())
+	}
+	if tcp.ResponseTimeHistogram.TotalCount() != 1 || tcp.ResponseTimeHistogram.Max() != 20 {
+		t.Errorf("tcp: %d responses up to %dms", tcp.ResponseTimeHistogram.TotalCount(), tcp.ResponseTimeHistogram.Max())
+	}
+	if udp.Exchanges != 2 || udp.ConnectHistogram.TotalCount() != 0 || udp.ResponseTimeHistogram.TotalCount() != 1 {
+		t.Errorf("udp: %d exchanges, %d connects, %d responses", udp.Exchanges, udp.ConnectHistogram.TotalCount(), udp.ResponseTimeHistogram.TotalCount())
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"goload/types"
+	"time"
+)
+
+// Result is the outcome of a single request, as streamed to the outputs.
+type Result struct {
+	Timestamp time.Time
+	VU        int
+	Iteration int
+	Test      string
+	Phase     string
+	Name      string
+	Method    string
+	URL       string
+	Protocol  string
+	Status    int
+	Failed    bool
+	NoLatency bool // Duration is not a request latency, see RequestMetric.HasLatency
+	Duration  time.Duration
+	Timing    types.RequestTiming
+	BytesSent int64
+	BytesRecv int64
+	Error     string
+	Tags      map[string]string
+	TraceID   string // Set when the request was traced, see the traceparent header
+	SpanID    string
+}
+
+// Output receives the result of every request. WriteResult is called by the
+// VUs and must not block them; Close is called once the run is over.
+type Output interface {
+	WriteResult(result Result)
+	Close() error
+}
+
+// AddOutput registers output. Outputs must be added before the run starts.
+func (collector *MetricsCollector) AddOutput(output Output) {
+	collector.outputs = append(collector.outputs, output)
+}
+
+func (collector *MetricsCollector) PublishResult(result Result) {
+	for _, output := range collector.outputs {
+		output.WriteResult(result)
+	}
+}
+
+// CloseOutputs closes the outputs, returning the first error.
+func (collector *MetricsCollector) CloseOutputs() error {
+	var firstErr error
+	for _, output := range collector.outputs {
+		if err := output.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	collector.outputs = nil
+	return firstErr
+}
+
+// RequestStarted and RequestFinished count the requests in flight.
+func (collector *MetricsCollector) RequestStarted() {
+	collector.inFlightRequests.Add(1)
+}
+
+func (collector *MetricsCollector) RequestFinished() {
+	collector.inFlightRequests.Add(-1)
+}
+
+func (collector *MetricsCollector) ActiveVUs() int64 {
+	return collector.activeVUs.Load()
+}
+
+func (collector *MetricsCollector) InFlightRequests() int64 {
+	return collector.inFlightRequests.Load()
+}
+
+// QueueDepth is the number of metrics waiting for a metric worker.
+func (collector *MetricsCollector) QueueDepth() int {
+	if collector.MetricWorkerPool == nil {
+		return 0
+	}
+	_, queueSize := collector.MetricWorkerPool.GetStats()
+	return queueSize
+}
@@ -0,0 +1,421 @@
+package metrics
+
+import (
+	"fmt"
+	"goload/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// SummarySchemaVersion is bumped whenever a field of Summary is renamed or
+// removed; new fields can be added without bumping it.
+const SummarySchemaVersion = 1
+
+// Summary is the machine readable summary of a run. Latencies are in
+// milliseconds, rates in percents.
+type Summary struct {
+	SchemaVersion int                `json:"schema_version"`
+	Collection    string             `json:"collection"`
+	StartedAt     time.Time          `json:"started_at"`
+	EndedAt       time.Time          `json:"ended_at"`
+	DurationSecs  float64            `json:"duration_seconds"`
+	Passed        bool               `json:"passed"`
+	Requests      RequestSummary     `json:"requests"`
+	Latency       LatencySummary     `json:"latency_ms"`
+	Bytes         BytesSummary       `json:"bytes"`
+	Timing        map[string]Latency `json:"timing_ms,omitempty"`
+	Checks        CheckTotals        `json:"checks"`
+	CheckResults  []CheckSummary     `json:"check_results,omitempty"`
+	Thresholds    []ThresholdResult  `json:"thresholds,omitempty"`
+	Tests         []TestSummary      `json:"tests"`
+	Groups        []GroupSummary     `json:"groups,omitempty"`
+	Errors        []ErrorSummary     `json:"errors,omitempty"`
+	Timeseries    []Interval         `json:"timeseries,omitempty"`
+	Config        string             `json:"config,omitempty"`
+	Auth          *AuthSummary       `json:"auth,omitempty"`
+	GraphQL       []GraphQLSummary   `json:"graphql,omitempty"`
+	WebSocket     *WebSocketSummary  `json:"websocket,omitempty"`
+	GRPC          []GRPCSummary      `json:"grpc,omitempty"`
+	SSE           *SSESummary        `json:"sse,omitempty"`
+	Sockets       []SocketSummary    `json:"sockets,omitempty"`
+	RateLimits    []RateLimitSummary `json:"rate_limits,omitempty"`
+}
+
+type RequestSummary struct {
+	Total           int64   `json:"total"`
+	Successes       int64   `json:"successes"`
+	Fails           int64   `json:"fails"`
+	RPS             float64 `json:"rps"`
+	ErrorRatePct    float64 `json:"error_rate_pct"`
+	AvailabilityPct float64 `json:"availability_pct"`
+}
+
+type LatencySummary struct {
+	Min  int64   `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  int64   `json:"p50"`
+	P75  int64   `json:"p75"`
+	P90  int64   `json:"p90"`
+	P95  int64   `json:"p95"`
+	P99  int64   `json:"p99"`
+	Max  int64   `json:"max"`
+}
+
+// Latency is the short form of LatencySummary used by the detailed sections.
+type Latency struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+type BytesSummary struct {
+	Sent     int64 `json:"sent"`
+	Received int64 `json:"received"`
+	Decoded  int64 `json:"decoded"`
+}
+
+type CheckTotals struct {
+	Total   int64   `json:"total"`
+	Passes  int64   `json:"passes"`
+	Fails   int64   `json:"fails"`
+	PassPct float64 `json:"pass_pct"`
+}
+
+type CheckSummary struct {
+	Id     string `json:"id"`
+	Passes int64  `json:"passes"`
+	Fails  int64  `json:"fails"`
+}
+
+type TestSummary struct {
+	Name string `json:"name"`
+	TestStats
+	CheckResults []CheckSummary `json:"check_results,omitempty"`
+	Passed       bool           `json:"passed"`
+}
+
+// ErrorSummary counts the failed requests sharing an error, or a status code
+// when they got a response.
+type ErrorSummary struct {
+	Error string `json:"error"`
+	Count int64  `json:"count"`
+}
+
+// GroupSummary is a breakdown of the requests by the tags of By.
+type GroupSummary struct {
+	By   []string   `json:"by"`
+	Rows []GroupRow `json:"rows"`
+}
+
+type GroupRow struct {
+	Tags     map[string]string `json:"tags"`
+	Requests RequestSummary    `json:"requests"`
+	Latency  LatencySummary    `json:"latency_ms"`
+}
+
+type AuthSummary struct {
+	Fetches int64   `json:"fetches"`
+	Fails   int64   `json:"fails"`
+	Latency Latency `json:"latency_ms"`
+}
+
+type GraphQLSummary struct {
+	Operation      string `json:"operation"`
+	Requests       int64  `json:"requests"`
+	FailedRequests int64  `json:"failed_requests"`
+	Errors         int64  `json:"errors"`
+}
+
+type WebSocketSummary struct {
+	WebSocketStats
+	Connect   Latency `json:"connect_ms"`
+	RoundTrip Latency `json:"round_trip_ms"`
+}
+
+type GRPCSummary struct {
+	Method string `json:"method"`
+	GRPCStats
+}
+
+type SSESummary struct {
+	SSEStats
+	FirstEvent Latency `json:"first_event_ms"`
+	Gap        Latency `json:"gap_ms"`
+}
+
+type SocketSummary struct {
+	Protocol  string  `json:"protocol"`
+	Exchanges int64   `json:"exchanges"`
+	Timeouts  int64   `json:"timeouts"`
+	Connect   Latency `json:"connect_ms"`
+	Response  Latency `json:"response_ms"`
+}
+
+type RateLimitSummary struct {
+	Key       string  `json:"key"`
+	Requests  int64   `json:"requests"`
+	Throttled int64   `json:"throttled"`
+	Wait      Latency `json:"wait_ms"`
+}
+
+// RunInfo describes the run being summarized.
+type RunInfo struct {
+	Collection string
+	Config     string // Snapshot of the configuration, secrets redacted
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Tests      []TestRun
+}
+
+// TestRun describes a test of the run and its thresholds.
+type TestRun struct {
+	Name       string
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Thresholds *types.Thresholds
+}
+
+// Summary builds the summary of the run, evaluating the thresholds of its
+// tests. The breakdowns cover the tests, their phases and the GroupBy tags.
+func (collector *MetricsCollector) Summary(run RunInfo) Summary {
+	collector.requestLatencyHistogramMutex.Lock()
+	defer collector.requestLatencyHistogramMutex.Unlock()
+
+	duration := run.EndedAt.Sub(run.StartedAt)
+	summary := Summary{
+		SchemaVersion: SummarySchemaVersion,
+		Collection:    run.Collection,
+		StartedAt:     run.StartedAt,
+		EndedAt:       run.EndedAt,
+		DurationSecs:  duration.Seconds(),
+		Passed:        true,
+		Requests:      requestSummary(collector.totalRequests, collector.totalSuccesses, collector.totalFails, duration),
+		Latency:       latencySummary(collector.requestLatencyHistogram),
+		Bytes: BytesSummary{
+			Sent:     collector.totalBytesSent,
+			Received: collector.totalBytesRecv,
+			Decoded:  collector.totalBytesRecvDecoded,
+		},
+		Checks: checkTotals(CheckStats{Passes: collector.totalChecks - collector.totalCheckFails, Fails: collector.totalCheckFails}),
+		Config: run.Config,
+	}
+	if collector.timeseries != nil {
+		summary.Timeseries = collector.timeseries.intervals()
+	}
+	for message, count := range collector.errors {
+		summary.Errors = append(summary.Errors, ErrorSummary{Error: message, Count: count})
+	}
+	sort.Slice(summary.Errors, func(i, j int) bool {
+		if summary.Errors[i].Count != summary.Errors[j].Count {
+			return summary.Errors[i].Count > summary.Errors[j].Count
+		}
+		return summary.Errors[i].Error < summary.Errors[j].Error
+	})
+
+	for _, phase := range timingPhases {
+		histogram := collector.timingHistograms[phase]
+		if histogram.TotalCount() == 0 {
+			continue
+		}
+		if summary.Timing == nil {
+			summary.Timing = make(map[string]Latency)
+		}
+		key := strings.ReplaceAll(strings.ToLower(phase), " ", "_")
+		summary.Timing[key] = latencyFrom(histogram, 1000)
+	}
+	for _, id := range sortedKeys(collector.checks) {
+		stats := collector.checks[id]
+		summary.CheckResults = append(summary.CheckResults, CheckSummary{Id: id, Passes: stats.Passes, Fails: stats.Fails})
+	}
+
+	tests := make(map[string]*TagGroup)
+	for _, group := range collector.group([]string{"test"}) {
+		tests[group.Tags["test"]] = group
+	}
+	for _, test := range run.Tests {
+		stats := TestStats{StartedAt: test.StartedAt, EndedAt: test.EndedAt}
+		if group, ok := tests[test.Name]; ok {
+			stats.Requests = requestSummary(group.Requests, group.Successes, group.Fails, test.EndedAt.Sub(test.StartedAt))
+			stats.Latency = latencySummary(group.Histogram)
+		}
+		testSummary := TestSummary{Name: test.Name, Passed: true}
+		if checks, ok := collector.testChecks[test.Name]; ok {
+			var totals CheckStats
+			for _, id := range sortedKeys(checks) {
+				totals.Passes += checks[id].Passes
+				totals.Fails += checks[id].Fails
+				testSummary.CheckResults = append(testSummary.CheckResults, CheckSummary{Id: id, Passes: checks[id].Passes, Fails: checks[id].Fails})
+			}
+			stats.Checks = checkTotals(totals)
+		}
+		testSummary.TestStats = stats
+		if test.Thresholds != nil {
+			for _, result := range evaluateThresholds(test.Name, &stats, test.Thresholds) {
+				summary.Thresholds = append(summary.Thresholds, result)
+				if !result.Passed {
+					testSummary.Passed = false
+					summary.Passed = false
+				}
+			}
+		}
+		summary.Tests = append(summary.Tests, testSummary)
+	}
+
+	for _, names := range collector.summaryGroups() {
+		groupSummary := GroupSummary{By: names}
+		for _, group := range collector.group(names) {
+			groupSummary.Rows = append(groupSummary.Rows, GroupRow{
+				Tags:     group.Tags,
+				Requests: requestSummary(group.Requests, group.Successes, group.Fails, duration),
+				Latency:  latencySummary(group.Histogram),
+			})
+		}
+		summary.Groups = append(summary.Groups, groupSummary)
+	}
+
+	if collector.totalAuthFetches > 0 {
+		summary.Auth = &AuthSummary{
+			Fetches: collector.totalAuthFetches,
+			Fails:   collector.totalAuthFails,
+			Latency: latencyFrom(collector.authLatencyHistogram, 1),
+		}
+	}
+	for _, operation := range sortedKeys(collector.graphQLOperations) {
+		stats := collector.graphQLOperations[operation]
+		summary.GraphQL = append(summary.GraphQL, GraphQLSummary{
+			Operation:      operation,
+			Requests:       stats.Requests,
+			FailedRequests: stats.FailedRequests,
+			Errors:         stats.Errors,
+		})
+	}
+	if collector.webSocketStats.Sessions > 0 {
+		summary.WebSocket = &WebSocketSummary{
+			WebSocketStats: collector.webSocketStats,
+			Connect:        latencyFrom(collector.webSocketConnectHistogram, 1),
+			RoundTrip:      latencyFrom(collector.webSocketRoundTripHistogram, 1),
+		}
+	}
+	for _, method := range sortedKeys(collector.grpcMethods) {
+		summary.GRPC = append(summary.GRPC, GRPCSummary{Method: method, GRPCStats: *collector.grpcMethods[method]})
+	}
+	if collector.sseStats.Streams > 0 {
+		summary.SSE = &SSESummary{
+			SSEStats:   collector.sseStats,
+			FirstEvent: latencyFrom(collector.sseFirstEventHistogram, 1),
+			Gap:        latencyFrom(collector.sseGapHistogram, 1),
+		}
+	}
+	for _, protocol := range sortedKeys(collector.sockets) {
+		stats := collector.sockets[protocol]
+		summary.Sockets = append(summary.Sockets, SocketSummary{
+			Protocol:  protocol,
+			Exchanges: stats.Exchanges,
+			Timeouts:  stats.Timeouts,
+			Connect:   latencyFrom(stats.ConnectHistogram, 1),
+			Response:  latencyFrom(stats.ResponseTimeHistogram, 1),
+		})
+	}
+	for _, key := range sortedKeys(collector.rateLimits) {
+		stats := collector.rateLimits[key]
+		summary.RateLimits = append(summary.RateLimits, RateLimitSummary{
+			Key:       key,
+			Requests:  stats.Requests,
+			Throttled: stats.Throttled,
+			Wait:      latencyFrom(stats.WaitHistogram, 1),
+		})
+	}
+	return summary
+}
+
+// summaryGroups are the breakdowns of the summary: by test, by test and
+// phase, by request, then the GroupBy ones.
+func (collector *MetricsCollector) summaryGroups() [][]string {
+	groups := [][]string{{"test"}, {"test", "phase"}, {"test", "name", "method", "url"}}
+	seen := map[string]bool{"test": true, "test,phase": true, "test,name,method,url": true}
+	for _, names := range collector.GroupBy {
+		key := strings.Join(names, ",")
+		if !seen[key] {
+			seen[key] = true
+			groups = append(groups, names)
+		}
+	}
+	return groups
+}
+
+func requestSummary(total int64, successes int64, fails int64, duration time.Duration) RequestSummary {
+	summary := RequestSummary{Total: total, Successes: successes, Fails: fails}
+	if duration > 0 {
+		summary.RPS = float64(total) / duration.Seconds()
+	}
+	if total > 0 {
+		summary.ErrorRatePct = float64(fails) / float64(total) * 100
+		summary.AvailabilityPct = float64(successes) / float64(total) * 100
+	}
+	return summary
+}
+
+func latencySummary(histogram *hdrhistogram.Histogram) LatencySummary {
+	return LatencySummary{
+		Min:  histogram.Min(),
+		Mean: histogram.Mean(),
+		P50:  histogram.ValueAtQuantile(50),
+		P75:  histogram.ValueAtQuantile(75),
+		P90:  histogram.ValueAtQuantile(90),
+		P95:  histogram.ValueAtQuantile(95),
+		P99:  histogram.ValueAtQuantile(99),
+		Max:  histogram.Max(),
+	}
+}
+
+// latencyFrom summarizes histogram, whose values are divided by unit to get
+// milliseconds.
+func latencyFrom(histogram *hdrhistogram.Histogram, unit float64) Latency {
+	return Latency{
+		Count: histogram.TotalCount(),
+		P50:   float64(histogram.ValueAtQuantile(50)) / unit,
+		P95:   float64(histogram.ValueAtQuantile(95)) / unit,
+		Max:   float64(histogram.Max()) / unit,
+	}
+}
+
+func checkTotals(stats CheckStats) CheckTotals {
+	totals := CheckTotals{Total: stats.Passes + stats.Fails, Passes: stats.Passes, Fails: stats.Fails}
+	if totals.Total > 0 {
+		totals.PassPct = float64(stats.Passes) / float64(totals.Total) * 100
+	}
+	return totals
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ThresholdsTable prints the threshold results of the summary.
+func (s Summary) ThresholdsTable() string {
+	if len(s.Thresholds) == 0 {
+		return ""
+	}
+	table := "\nThresholds:\n"
+	for _, result := range s.Thresholds {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		detail := fmt.Sprintf("value=%.2f", result.Value)
+		if result.Error != "" {
+			detail = "error=" + result.Error
+		}
+		table += fmt.Sprintf("  %-4s %-30s %-8s %-24s %-10s %s\n", status, result.Test, result.Kind, result.Metric, result.Target, detail)
+	}
+	return table
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"fmt"
+	"goload/types"
+	"sort"
+	"strings"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// TagGroup holds the request stats of the requests sharing Tags. The
+// collector keeps one per distinct tag set, called a series, and merges
+// series into groups for the breakdowns.
+type TagGroup struct {
+	Tags      map[string]string
+	Requests  int64
+	Successes int64
+	Fails     int64
+	Histogram *hdrhistogram.Histogram
+}
+
+func newTagGroup(tags map[string]string) *TagGroup {
+	return &TagGroup{
+		Tags:      tags,
+		Histogram: hdrhistogram.New(1, 60_000_000, 3),
+	}
+}
+
+func (g *TagGroup) record(metric types.RequestMetric) {
+	if metric.HasLatency() {
+		_ = g.Histogram.RecordValue(metric.Duration.Milliseconds())
+	}
+	g.Requests++
+	if metric.Succeeded() {
+		g.Successes++
+	} else {
+		g.Fails++
+	}
+}
+
+func (g *TagGroup) merge(other *TagGroup) {
+	g.Histogram.Merge(other.Histogram)
+	g.Requests += other.Requests
+	g.Successes += other.Successes
+	g.Fails += other.Fails
+}
+
+// tagKey is the canonical form of tags, restricted to names when not nil.
+func tagKey(tags map[string]string, names []string) string {
+	if names == nil {
+		for name := range tags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+tags[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// maxSeries bounds the memory held by the series, each having its own
+// histogram. The tag sets seen past the limit are merged into an "other"
+// series per test and phase.
+const maxSeries = 500
+
+// recordSeries must be called with the collector lock held.
+func (collector *MetricsCollector) recordSeries(metric types.RequestMetric) {
+	key := tagKey(metric.Tags, nil)
+	series, ok := collector.series[key]
+	if !ok {
+		tags := metric.Tags
+		if len(collector.series) >= maxSeries {
+			tags = overflowTags(metric.Tags)
+			key = tagKey(tags, nil)
+			series, ok = collector.series[key]
+		}
+		if !ok {
+			series = newTagGroup(tags)
+			collector.series[key] = series
+		}
+	}
+	series.record(metric)
+}
+
+// overflowTags keeps the test and phase of tags, the other values become
+// "other".
+func overflowTags(tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(tags))
+	for name, value := range tags {
+		if name != "test" && name != "phase" {
+			value = "other"
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// Group merges the series sharing the same values of the tags names, in the
+// order of their values.
+func (collector *MetricsCollector) Group(names []string) []*TagGroup {
+	collector.requestLatencyHistogramMutex.Lock()
+	defer collector.requestLatencyHistogramMutex.Unlock()
+	return collector.group(names)
+}
+
+func (collector *MetricsCollector) group(names []string) []*TagGroup {
+	groups := make(map[string]*TagGroup)
+	for _, series := range collector.series {
+		key := tagKey(series.Tags, names)
+		group, ok := groups[key]
+		if !ok {
+			tags := make(map[string]string, len(names))
+			for _, name := range names {
+				tags[name] = series.Tags[name]
+			}
+			group = newTagGroup(tags)
+			groups[key] = group
+		}
+		group.merge(series)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*TagGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, groups[key])
+	}
+	return result
+}
+
+// groupTable prints the breakdown of the requests by the tags names.
+func (collector *MetricsCollector) groupTable(names []string) string {
+	table := fmt.Sprintf("\nRequests by %s:\n", strings.Join(names, ", "))
+	for _, group := range collector.group(names) {
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, name+"="+group.Tags[name])
+		}
+		table += fmt.Sprintf("  %-50s requests=%-9d fails=%-9d p50=%-6d p95=%-6d p99=%-6d max=%d\n",
+			strings.Join(values, " "), group.Requests, group.Fails,
+			group.Histogram.ValueAtQuantile(50),
+			group.Histogram.ValueAtQuantile(95),
+			group.Histogram.ValueAtQuantile(99),
+			group.Histogram.Max())
+	}
+	return table
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"goload/types"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGroupMergesSeries(t *testing.T) {
+	collector := &MetricsCollector{}
+	_ = collector.Init()
+	record := func(phase string, status int, duration time.Duration) {
+		collector.recordSeries(types.RequestMetric{
+			Duration:   duration,
+			StatusCode: status,
+			Tags:       map[string]string{"test": "shop", "phase": phase, "status": strconv.Itoa(status)},
+		})
+	}
+	record("ramp", 200, 10*time.Millisecond)
+	record("ramp", 500, 30*time.Millisecond)
+	record("peak", 200, 20*time.Millisecond)
+
+	groups := collector.Group([]string{"test", "phase"})
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	peak, ramp := groups[0], groups[1]
+	if peak.Tags["phase"] != "peak" || peak.Requests != 1 || peak.Fails != 0 {
+		t.Errorf("unexpected peak group %+v", peak)
+	}
+	if ramp.Tags["phase"] != "ramp" || ramp.Requests != 2 || ramp.Fails != 1 || ramp.Histogram.Max() < 30 {
+		t.Errorf("unexpected ramp group %+v", ramp)
+	}
+	if total := collector.Group([]string{}); len(total) != 1 || total[0].Requests != 3 {
+		t.Errorf("unexpected overall group %+v", total)
+	}
+}
+
+func TestSeriesAreCapped(t *testing.T) {
+	collector := &MetricsCollector{}
+	_ = collector.Init()
+	for i := 0; i < maxSeries+100; i++ {
+		collector.recordSeries(types.RequestMetric{
+			Duration:   time.Millisecond,
+			StatusCode: 200,
+			Tags:       map[string]string{"test": "shop", "phase": "peak", "url": "/orders/" + strconv.Itoa(i)},
+		})
+	}
+	if len(collector.series) != maxSeries+1 {
+		t.Errorf("got %d series, want %d", len(collector.series), maxSeries+1)
+	}
+	other := collector.series[tagKey(map[string]string{"test": "shop", "phase": "peak", "url": "other"}, nil)]
+	if other == nil || other.Requests != 100 {
+		t.Errorf("unexpected other series %+v", other)
+	}
+	if groups := collector.Group([]string{"phase"}); len(groups) != 1 || groups[0].Requests != maxSeries+100 {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+}
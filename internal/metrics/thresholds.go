@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"goload/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThresholdResult is the outcome of a threshold condition of a test.
+type ThresholdResult struct {
+	Test   string  `json:"test"`
+	Kind   string  `json:"kind"` // pass_if or fail_if
+	Metric string  `json:"metric"`
+	Target string  `json:"target"`
+	Value  float64 `json:"value"`
+	Passed bool    `json:"passed"`
+	Error  string  `json:"error,omitempty"`
+}
+
+var thresholdOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// evaluateThresholds checks the thresholds of test against its stats.
+func evaluateThresholds(test string, stats *TestStats, thresholds *types.Thresholds) []ThresholdResult {
+	var results []ThresholdResult
+	evaluate := func(kind string, conditions []types.ThresholdCondition) {
+		for _, condition := range conditions {
+			result := ThresholdResult{Test: test, Kind: kind, Metric: condition.Metric, Target: condition.Target}
+			value, err := stats.metric(condition.Metric)
+			var holds bool
+			if err == nil {
+				result.Value = value
+				holds, err = compareTarget(value, condition.Target)
+			}
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Passed = holds == (kind == "pass_if")
+			}
+			results = append(results, result)
+		}
+	}
+	evaluate("pass_if", thresholds.PassIf)
+	evaluate("fail_if", thresholds.FailIf)
+	return results
+}
+
+// compareTarget tells whether value satisfies target, an operator followed by
+// a number. A trailing % is accepted, the percent metrics being in percents.
+func compareTarget(value float64, target string) (bool, error) {
+	target = strings.TrimSpace(target)
+	operator := "=="
+	for _, candidate := range thresholdOperators {
+		if strings.HasPrefix(target, candidate) {
+			operator = candidate
+			break
+		}
+	}
+	number := strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(target, operator)), "%")
+	expected, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid threshold target %q", target)
+	}
+	switch operator {
+	case "<=":
+		return value <= expected, nil
+	case ">=":
+		return value >= expected, nil
+	case "<":
+		return value < expected, nil
+	case ">":
+		return value > expected, nil
+	case "!=":
+		return value != expected, nil
+	default:
+		return value == expected, nil
+	}
+}
+
+// TestStats are the stats of a test the thresholds are evaluated against.
+type TestStats struct {
+	Requests  RequestSummary `json:"requests"`
+	Latency   LatencySummary `json:"latency_ms"`
+	Checks    CheckTotals    `json:"checks"`
+	StartedAt time.Time      `json:"started_at"`
+	EndedAt   time.Time      `json:"ended_at"`
+}
+
+func (s *TestStats) metric(name string) (float64, error) {
+	switch name {
+	case "requests":
+		return float64(s.Requests.Total), nil
+	case "rps":
+		return s.Requests.RPS, nil
+	case "error_rate_pct":
+		return s.Requests.ErrorRatePct, nil
+	case "availability", "availability_pct":
+		return s.Requests.AvailabilityPct, nil
+	case "checks_pass_pct":
+		return s.Checks.PassPct, nil
+	}
+	if field, ok := strings.CutPrefix(name, "latency_ms."); ok {
+		switch field {
+		case "min":
+			return float64(s.Latency.Min), nil
+		case "mean":
+			return s.Latency.Mean, nil
+		case "p50":
+			return float64(s.Latency.P50), nil
+		case "p75":
+			return float64(s.Latency.P75), nil
+		case "p90":
+			return float64(s.Latency.P90), nil
+		case "p95":
+			return float64(s.Latency.P95), nil
+		case "p99":
+			return float64(s.Latency.P99), nil
+		case "max":
+			return float64(s.Latency.Max), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown threshold metric %q", name)
+}
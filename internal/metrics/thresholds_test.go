@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"goload/types"
+	"testing"
+)
+
+func TestCompareTarget(t *testing.T) {
+	tests := []struct {
+		value  float64
+		target string
+		want   bool
+	}{
+		{200, "<=200", true},
+		{201, "<=200", false},
+		{200, "<200", false},
+		{199.5, "< 200", true},
+		{95, ">=95%", true},
+		{94.9, ">=95%", false},
+		{96, "> 95 %", true},
+		{95, ">95", false},
+		{0, "==0", true},
+		{0, "0", true},
+		{1, "0", false},
+		{1, "!=0", true},
+		{0, "!=0", false},
+	}
+	for _, test := range tests {
+		got, err := compareTarget(test.value, test.target)
+		if err != nil {
+			t.Errorf("%v %s: %s", test.value, test.target, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%v %s: got %t, want %t", test.value, test.target, got, test.want)
+		}
+	}
+	for _, target := range []string{"", "<=", "fast", "<=2s"} {
+		if _, err := compareTarget(1, target); err == nil {
+			t.Errorf("%q: expected an error", target)
+		}
+	}
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	stats := &TestStats{
+		Requests: RequestSummary{Total: 100, ErrorRatePct: 2, AvailabilityPct: 98},
+		Latency:  LatencySummary{P95: 180, Max: 900},
+	}
+	results := evaluateThresholds("checkout", stats, &types.Thresholds{
+		PassIf: []types.ThresholdCondition{
+			{Metric: "latency_ms.p95", Target: "<=200"},
+			{Metric: "availability", Target: ">=99%"},
+		},
+		FailIf: []types.ThresholdCondition{
+			{Metric: "latency_ms.max", Target: ">1000"},
+			{Metric: "latency_ms.p42", Target: ">1"},
+		},
+	})
+	want := []struct {
+		passed bool
+		value  float64
+		failed bool
+	}{
+		{true, 180, false},
+		{false, 98, false},
+		{true, 900, false},
+		{false, 0, true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Passed != want[i].passed || result.Value != want[i].value || (result.Error != "") != want[i].failed {
+			t.Errorf("%s %s %s: unexpected result %+v", result.Kind, result.Metric, result.Target, result)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"goload/types"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const DefaultMaxIntervals = 3600
+
+// Interval holds the request stats of one interval of the run. Latencies are
+// in milliseconds.
+type Interval struct {
+	Start     time.Time `json:"start"`
+	Requests  int64     `json:"requests"`
+	Fails     int64     `json:"fails"`
+	RPS       float64   `json:"rps"`
+	ErrorRate float64   `json:"error_rate"`
+	ActiveVUs int64     `json:"active_vus"`
+	P50       int64     `json:"p50"`
+	P90       int64     `json:"p90"`
+	P95       int64     `json:"p95"`
+	P99       int64     `json:"p99"`
+	Max       int64     `json:"max"`
+}
+
+// timeseries aggregates the requests into fixed intervals. Only the current
+// interval has a histogram; closed intervals keep their percentiles, the
+// last maxIntervals of them in a ring, and are streamed to the output.
+type timeseries struct {
+	interval     time.Duration
+	maxIntervals int
+	current      Interval
+	histogram    *hdrhistogram.Histogram
+	closed       []Interval
+	next         int
+	total        int
+	output       *bufio.Writer
+	closer       io.Closer
+	csv          bool
+}
+
+// EnableTimeseries records the request stats per interval. Closed intervals
+// are written to output as CSV when path ends with .csv, as JSON lines
+// otherwise; output can be nil.
+func (collector *MetricsCollector) EnableTimeseries(interval time.Duration, maxIntervals int, path string, output io.WriteCloser) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid timeseries interval: %s", interval)
+	}
+	if maxIntervals <= 0 {
+		maxIntervals = DefaultMaxIntervals
+	}
+	series := &timeseries{
+		interval:     interval,
+		maxIntervals: maxIntervals,
+		histogram:    hdrhistogram.New(1, 60_000_000, 3),
+		closed:       make([]Interval, 0, min(maxIntervals, 1024)),
+	}
+	if output != nil {
+		series.output = bufio.NewWriter(output)
+		series.closer = output
+		series.csv = strings.EqualFold(filepath.Ext(path), ".csv")
+		if series.csv {
+			_, _ = series.output.WriteString("start,requests,fails,rps,error_rate,active_vus,p50,p90,p95,p99,max\n")
+		}
+	}
+	collector.requestLatencyHistogramMutex.Lock()
+	collector.timeseries = series
+	collector.requestLatencyHistogramMutex.Unlock()
+	return nil
+}
+
+// SetActiveVUs reports the number of VUs currently running.
+func (collector *MetricsCollector) SetActiveVUs(vus int) {
+	collector.activeVUs.Store(int64(vus))
+}
+
+// Intervals returns the intervals kept in memory, oldest first.
+func (collector *MetricsCollector) Intervals() []Interval {
+	collector.requestLatencyHistogramMutex.Lock()
+	defer collector.requestLatencyHistogramMutex.Unlock()
+	if collector.timeseries == nil {
+		return nil
+	}
+	return collector.timeseries.intervals()
+}
+
+// CloseTimeseries closes the current interval and the output.
+func (collector *MetricsCollector) CloseTimeseries() error {
+	collector.requestLatencyHistogramMutex.Lock()
+	defer collector.requestLatencyHistogramMutex.Unlock()
+	series := collector.timeseries
+	if series == nil {
+		return nil
+	}
+	if !series.current.Start.IsZero() {
+		series.close()
+		series.current = Interval{}
+	}
+	if series.output == nil {
+		return nil
+	}
+	if err := series.output.Flush(); err != nil {
+		return err
+	}
+	return series.closer.Close()
+}
+
+// record must be called with the collector lock held.
+func (s *timeseries) record(metric types.RequestMetric, activeVUs int64) {
+	timestamp := metric.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	start := timestamp.Truncate(s.interval)
+	if s.current.Start.IsZero() {
+		s.current.Start = start
+	}
+	// Samples of closed intervals, late by a few ms, go to the current one
+	for start.After(s.current.Start) {
+		s.close()
+		s.current = Interval{Start: s.current.Start.Add(s.interval), ActiveVUs: activeVUs}
+	}
+
+	if metric.HasLatency() {
+		_ = s.histogram.RecordValue(metric.Duration.Milliseconds())
+	}
+	s.current.Requests++
+	if !metric.Succeeded() {
+		s.current.Fails++
+	}
+	s.current.ActiveVUs = max(s.current.ActiveVUs, activeVUs)
+}
+
+func (s *timeseries) close() {
+	interval := s.current
+	interval.RPS = float64(interval.Requests) / s.interval.Seconds()
+	if interval.Requests > 0 {
+		interval.ErrorRate = float64(interval.Fails) / float64(interval.Requests)
+		interval.P50 = s.histogram.ValueAtQuantile(50)
+		interval.P90 = s.histogram.ValueAtQuantile(90)
+		interval.P95 = s.histogram.ValueAtQuantile(95)
+		interval.P99 = s.histogram.ValueAtQuantile(99)
+		interval.Max = s.histogram.Max()
+	}
+	s.histogram.Reset()
+
+	if len(s.closed) < s.maxIntervals {
+		s.closed = append(s.closed, interval)
+	} else {
+		s.closed[s.next] = interval
+	}
+	s.next = (s.next + 1) % s.maxIntervals
+	s.total++
+	s.write(interval)
+}
+
+func (s *timeseries) write(interval Interval) {
+	if s.output == nil {
+		return
+	}
+	if s.csv {
+		_, _ = fmt.Fprintf(s.output, "%s,%d,%d,%.2f,%.4f,%d,%d,%d,%d,%d,%d\n",
+			interval.Start.Format(time.RFC3339Nano), interval.Requests, interval.Fails, interval.RPS, interval.ErrorRate,
+			interval.ActiveVUs, interval.P50, interval.P90, interval.P95, interval.P99, interval.Max)
+		return
+	}
+	encoded, _ := json.Marshal(interval)
+	_, _ = s.output.Write(append(encoded, '\n'))
+}
+
+func (s *timeseries) intervals() []Interval {
+	if len(s.closed) < s.maxIntervals {
+		return append([]Interval(nil), s.closed...)
+	}
+	return append(append([]Interval(nil), s.closed[s.next:]...), s.closed[:s.next]...)
+}
+
+// table summarizes the intervals kept in memory.
+func (s *timeseries) table() string {
+	intervals := s.intervals()
+	if len(intervals) == 0 {
+		return ""
+	}
+	peak, worst := intervals[0], intervals[0]
+	for _, interval := range intervals {
+		if interval.RPS > peak.RPS {
+			peak = interval
+		}
+		if interval.P95 > worst.P95 {
+			worst = interval
+		}
+	}
+	table := fmt.Sprintf("\nTime Series (%d intervals of %s):\n", s.total, s.interval)
+	table += fmt.Sprintf("  peak rps   %.1f at %s with %d VUs\n", peak.RPS, peak.Start.Format(time.TimeOnly), peak.ActiveVUs)
+	table += fmt.Sprintf("  worst p95  %dms at %s with %d VUs\n", worst.P95, worst.Start.Format(time.TimeOnly), worst.ActiveVUs)
+	return table
+}
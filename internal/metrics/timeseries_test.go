@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"goload/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestTimeseriesIntervals(t *testing.T) {
+	collector := &MetricsCollector{}
+	_ = collector.Init()
+	var output bytes.Buffer
+	if err := collector.EnableTimeseries(time.Second, 2, "series.csv", nopCloser{&output}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	record := func(offset time.Duration, duration time.Duration, status int) {
+		collector.requestLatencyHistogramMutex.Lock()
+		collector.timeseries.record(types.RequestMetric{Timestamp: start.Add(offset), Duration: duration, StatusCode: status, NotSent: status == 0}, 3)
+		collector.requestLatencyHistogramMutex.Unlock()
+	}
+	record(100*time.Millisecond, 10*time.Millisecond, 200)
+	record(900*time.Millisecond, 30*time.Millisecond, 500)
+	record(1500*time.Millisecond, 20*time.Millisecond, 200)
+	// An empty interval between the second and the last one
+	record(3200*time.Millisecond, 40*time.Millisecond, 200)
+	// Never sent, it has no latency
+	record(3300*time.Millisecond, 0, 0)
+	if err := collector.CloseTimeseries(); err != nil {
+		t.Fatal(err)
+	}
+
+	intervals := collector.Intervals()
+	if len(intervals) != 2 {
+		t.Fatalf("got %d intervals, want the last 2", len(intervals))
+	}
+	empty, last := intervals[0], intervals[1]
+	if !empty.Start.Equal(start.Add(2*time.Second)) || empty.Requests != 0 {
+		t.Errorf("unexpected empty interval %+v", empty)
+	}
+	if last.Requests != 2 || last.Fails != 1 || last.P50 != 40 || last.ActiveVUs != 3 {
+		t.Errorf("unexpected last interval %+v", last)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "start,requests,fails") {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+	if lines[1] != "2026-01-01T12:00:00Z,2,1,2.00,0.5000,3,10,30,30,30,30" {
+		t.Errorf("unexpected first interval %q", lines[1])
+	}
+}
+
+func TestTimeseriesRejectsInvalidInterval(t *testing.T) {
+	collector := &MetricsCollector{}
+	_ = collector.Init()
+	if err := collector.EnableTimeseries(0, 0, "", nil); err == nil {
+		t.Error("expected an error")
+	}
+}
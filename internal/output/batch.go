@@ -0,0 +1,179 @@
+package output
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"goload/internal/metrics"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RequestMode  = "request"  // Every request is sent
+	IntervalMode = "interval" // Aggregates are sent at every interval
+)
+
+// batchOutput runs the goroutine of the push outputs. Results are queued
+// without blocking the VUs, dropped when the queue is full, and handed to
+// handle; flush is called at every interval and once more on close.
+type batchOutput struct {
+	results chan metrics.Result
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+func startBatchOutput(bufferSize int, interval time.Duration, handle func(metrics.Result), flush func(time.Time)) *batchOutput {
+	if bufferSize <= 0 {
+		bufferSize = DefaultResultsBuffer
+	}
+	b := &batchOutput{
+		results: make(chan metrics.Result, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case result, ok := <-b.results:
+				if !ok {
+					flush(time.Now())
+					return
+				}
+				handle(result)
+			case now := <-ticker.C:
+				flush(now)
+			}
+		}
+	}()
+	return b
+}
+
+func (b *batchOutput) WriteResult(result metrics.Result) {
+	select {
+	case b.results <- result:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// stop handles the queued results, flushes and returns the number of results
+// dropped.
+func (b *batchOutput) stop() int64 {
+	close(b.results)
+	<-b.done
+	return b.dropped.Load()
+}
+
+// aggregate sums up the results of a tag set over an interval.
+type aggregate struct {
+	key       string
+	tags      []labelPair
+	requests  int64
+	failures  int64
+	bytesSent int64
+	bytesRecv int64
+	histogram *hdrhistogram.Histogram // Microseconds
+}
+
+// aggregates groups the results of the current interval by tag set. It is
+// only used by the goroutine of its output.
+type aggregates struct {
+	tags   []string // Tags kept, every tag when empty
+	groups map[string]*aggregate
+}
+
+func newAggregates(tags []string) *aggregates {
+	return &aggregates{tags: tags, groups: make(map[string]*aggregate)}
+}
+
+func (a *aggregates) record(result metrics.Result) {
+	tags := selectTags(a.tags, result.Tags)
+	key := aggregateKey(tags)
+	group, ok := a.groups[key]
+	if !ok && len(a.groups) >= maxSeries {
+		tags = overflowLabels(tags)
+		key = aggregateKey(tags)
+		group, ok = a.groups[key]
+	}
+	if !ok {
+		group = &aggregate{key: key, tags: tags, histogram: hdrhistogram.New(1, 60_000_000, 3)}
+		a.groups[key] = group
+	}
+	group.requests++
+	if result.Failed {
+		group.failures++
+	}
+	group.bytesSent += result.BytesSent
+	group.bytesRecv += result.BytesRecv
+	if !result.NoLatency {
+		_ = group.histogram.RecordValue(result.Duration.Microseconds())
+	}
+}
+
+func aggregateKey(tags []labelPair) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = tag.name + "=" + tag.value
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// take returns the aggregates of the interval, sorted by tag set, and starts
+// the next one.
+func (a *aggregates) take() []*aggregate {
+	taken := make([]*aggregate, 0, len(a.groups))
+	for _, group := range a.groups {
+		taken = append(taken, group)
+	}
+	sort.Slice(taken, func(i, j int) bool {
+		return taken[i].key < taken[j].key
+	})
+	a.groups = make(map[string]*aggregate)
+	return taken
+}
+
+// maxSeries bounds the tag sets an output keeps, like the series of the
+// collector. The tag sets seen past the limit are merged into an "other" set
+// per test and phase.
+const maxSeries = 500
+
+// overflowLabels keeps the test and phase of labels, the other values become
+// "other".
+func overflowLabels(labels []labelPair) []labelPair {
+	merged := make([]labelPair, len(labels))
+	for i, label := range labels {
+		if label.name != "test" && label.name != "phase" {
+			label.value = "other"
+		}
+		merged[i] = label
+	}
+	return merged
+}
+
+// selectTags returns the tags named by names, every tag when names is empty,
+// sorted by name.
+func selectTags(names []string, tags map[string]string) []labelPair {
+	if len(names) == 0 {
+		names = make([]string, 0, len(tags))
+		for name := range tags {
+			names = append(names, name)
+		}
+	}
+	selected := make([]labelPair, 0, len(names))
+	for _, name := range names {
+		if value, ok := tags[name]; ok {
+			selected = append(selected, labelPair{name: name, value: value})
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].name < selected[j].name
+	})
+	return selected
+}
+
+func microsToMillis(value int64) float64 {
+	return float64(value) / 1000
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// retryCount is the number of retries of a failed request, 3 when unset.
+func retryCount(retries *int) int {
+	if retries == nil {
+		return 3
+	}
+	return *retries
+}
+
+// postWithRetries posts body, retrying with an exponential backoff on network
+// errors, 429 and 5xx responses.
+func postWithRetries(client *http.Client, url string, header http.Header, body []byte, retries int) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(min(time.Duration(1<<(attempt-1))*500*time.Millisecond, 10*time.Second))
+		}
+		var retry bool
+		retry, err = post(client, url, header, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func post(client *http.Client, url string, header http.Header, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", "goload")
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(message))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
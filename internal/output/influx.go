@@ -0,0 +1,168 @@
+package output
+
+import (
+	"fmt"
+	"goload/internal/metrics"
+	"goload/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Influx writes the request metrics in the InfluxDB line protocol, either a
+// line per request or a line per tag set at every interval, plus a line with
+// the VU gauges at every interval.
+type Influx struct {
+	*batchOutput
+	config     types.InfluxConfig
+	stats      LiveStats
+	client     *http.Client
+	header     http.Header
+	aggregates *aggregates
+	lines      []string
+	failed     int
+	err        error
+}
+
+func NewInflux(config types.InfluxConfig, stats LiveStats) (*Influx, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Prefix == "" {
+		config.Prefix = "goload"
+	}
+	if config.Mode == "" {
+		config.Mode = IntervalMode
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 5000
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	if config.Token != "" {
+		header.Set("Authorization", "Token "+config.Token)
+	}
+	for name, value := range config.Headers {
+		header.Set(name, value)
+	}
+	o := &Influx{
+		config:     config,
+		stats:      stats,
+		client:     &http.Client{Timeout: config.Timeout},
+		header:     header,
+		aggregates: newAggregates(config.Tags),
+	}
+	o.batchOutput = startBatchOutput(config.BufferSize, config.Interval, o.handle, o.flush)
+	return o, nil
+}
+
+func (o *Influx) handle(result metrics.Result) {
+	if o.config.Mode == IntervalMode {
+		o.aggregates.record(result)
+		return
+	}
+	fields := []string{
+		"duration_ms=" + formatFloat(milliseconds(result.Duration)),
+		"dns_ms=" + formatFloat(milliseconds(result.Timing.DNS)),
+		"connect_ms=" + formatFloat(milliseconds(result.Timing.Connect)),
+		"tls_ms=" + formatFloat(milliseconds(result.Timing.TLS)),
+		"proxy_connect_ms=" + formatFloat(milliseconds(result.Timing.ProxyConnect)),
+		"first_byte_ms=" + formatFloat(milliseconds(result.Timing.FirstByte)),
+		"status_code=" + strconv.Itoa(result.Status) + "i",
+		"failed=" + strconv.FormatBool(result.Failed),
+		"bytes_sent=" + strconv.FormatInt(result.BytesSent, 10) + "i",
+		"bytes_received=" + strconv.FormatInt(result.BytesRecv, 10) + "i",
+		"vu=" + strconv.Itoa(result.VU) + "i",
+		"iteration=" + strconv.Itoa(result.Iteration) + "i",
+	}
+	if result.Error != "" {
+		fields = append(fields, "error="+fieldString(result.Error))
+	}
+	o.addLine(o.config.Prefix+"_requests", selectTags(o.config.Tags, result.Tags), fields, result.Timestamp)
+}
+
+func (o *Influx) flush(now time.Time) {
+	for _, group := range o.aggregates.take() {
+		histogram := group.histogram
+		o.addLine(o.config.Prefix+"_requests", group.tags, []string{
+			"requests=" + strconv.FormatInt(group.requests, 10) + "i",
+			"failures=" + strconv.FormatInt(group.failures, 10) + "i",
+			"bytes_sent=" + strconv.FormatInt(group.bytesSent, 10) + "i",
+			"bytes_received=" + strconv.FormatInt(group.bytesRecv, 10) + "i",
+			"mean_ms=" + formatFloat(histogram.Mean()/1000),
+			"p50_ms=" + formatFloat(microsToMillis(histogram.ValueAtQuantile(50))),
+			"p90_ms=" + formatFloat(microsToMillis(histogram.ValueAtQuantile(90))),
+			"p95_ms=" + formatFloat(microsToMillis(histogram.ValueAtQuantile(95))),
+			"p99_ms=" + formatFloat(microsToMillis(histogram.ValueAtQuantile(99))),
+			"max_ms=" + formatFloat(microsToMillis(histogram.Max())),
+		}, now)
+	}
+	o.addLine(o.config.Prefix+"_vus", nil, []string{
+		"active_vus=" + strconv.FormatInt(o.stats.ActiveVUs(), 10) + "i",
+		"in_flight_requests=" + strconv.FormatInt(o.stats.InFlightRequests(), 10) + "i",
+		"metric_queue_depth=" + strconv.Itoa(o.stats.QueueDepth()) + "i",
+	}, now)
+	o.send()
+}
+
+func (o *Influx) addLine(measurement string, tags []labelPair, fields []string, timestamp time.Time) {
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurement))
+	for _, tag := range tags {
+		if tag.value == "" {
+			// Influx rejects empty tag values
+			continue
+		}
+		b.WriteString("," + influxEscaper.Replace(tag.name) + "=" + influxEscaper.Replace(tag.value))
+	}
+	b.WriteString(" " + strings.Join(fields, ",") + " " + strconv.FormatInt(timestamp.UnixNano(), 10))
+	o.lines = append(o.lines, b.String())
+	if len(o.lines) >= o.config.BatchSize {
+		o.send()
+	}
+}
+
+func (o *Influx) send() {
+	if len(o.lines) == 0 {
+		return
+	}
+	body := []byte(strings.Join(o.lines, "\n") + "\n")
+	o.lines = o.lines[:0]
+	if err := postWithRetries(o.client, o.config.URL, o.header, body, retryCount(o.config.Retries)); err != nil {
+		o.failed++
+		o.err = err
+	}
+}
+
+// Close sends the last lines. The fields written by the goroutine are only
+// read once it is over.
+func (o *Influx) Close() error {
+	dropped := o.stop()
+	if o.err != nil {
+		return fmt.Errorf("%d influx writes failed, last error: %s", o.failed, o.err)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%d results dropped, influx could not keep up", dropped)
+	}
+	return nil
+}
+
+var measurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "\n", `\n`)
+
+// influxEscaper escapes tag keys and tag values.
+var influxEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+func fieldString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
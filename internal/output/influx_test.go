@@ -0,0 +1,73 @@
+package output
+
+import (
+	"goload/internal/metrics"
+	"goload/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInfluxEscaping(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	o, err := NewInflux(types.InfluxConfig{URL: server.URL, Token: "secret", Prefix: "load test", Mode: RequestMode, Interval: time.Hour}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.WriteResult(metrics.Result{
+		Timestamp: time.Unix(1, 0),
+		Duration:  1500 * time.Microsecond,
+		Status:    500,
+		Failed:    true,
+		Error:     "bad \"gateway\"\nupstream",
+		Tags:      map[string]string{"name": `a,b=c d\e`, "empty": ""},
+	})
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), lines)
+	}
+	want := `load\ test_requests,name=a\,b\=c\ d\\e duration_ms=1.5,dns_ms=0,connect_ms=0,tls_ms=0,proxy_connect_ms=0,first_byte_ms=0,` +
+		`status_code=500i,failed=true,bytes_sent=0i,bytes_received=0i,vu=0i,iteration=0i,error="bad \"gateway\"\nupstream" 1000000000`
+	if lines[0] != want {
+		t.Errorf("got\n%s\nwant\n%s", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[1], `load\ test_vus active_vus=4i,in_flight_requests=2i,metric_queue_depth=0i `) {
+		t.Errorf("got %s", lines[1])
+	}
+}
+
+func TestAggregatesAreCapped(t *testing.T) {
+	aggregates := newAggregates([]string{"test", "url"})
+	for i := 0; i < maxSeries+100; i++ {
+		aggregates.record(metrics.Result{Duration: time.Millisecond, Tags: map[string]string{"test": "shop", "url": "/items/" + strconv.Itoa(i)}})
+	}
+	taken := aggregates.take()
+	if len(taken) != maxSeries+1 {
+		t.Fatalf("got %d aggregates, want %d", len(taken), maxSeries+1)
+	}
+	other := taken[len(taken)-1]
+	if other.key != "test=shop\x00url=other" || other.requests != 100 {
+		t.Errorf("got %q with %d requests", other.key, other.requests)
+	}
+}
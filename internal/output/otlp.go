@@ -0,0 +1,323 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"goload/internal/metrics"
+	"goload/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpCumulative = 2 // AGGREGATION_TEMPORALITY_CUMULATIVE
+	otlpSpanClient = 3 // SPAN_KIND_CLIENT
+	otlpStatusOK   = 0 // STATUS_CODE_UNSET
+	otlpStatusFail = 2 // STATUS_CODE_ERROR
+)
+
+// OTLP exports the request metrics to an OpenTelemetry collector at every
+// interval with OTLP/HTTP JSON, and the client spans of the traced requests.
+// The metrics are cumulative from the start of the run, with the same series
+// as the Prometheus endpoint.
+type OTLP struct {
+	*batchOutput
+	config   types.OTLPConfig
+	stats    LiveStats
+	client   *http.Client
+	header   http.Header
+	series   *seriesSet
+	started  time.Time
+	resource otlpResource
+	spans    []otlpSpan
+	failed   int
+	err      error
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"` // int64 values are strings in OTLP JSON
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Unit        string         `json:"unit"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberPoint `json:"dataPoints"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type otlpHistogramPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func NewOTLP(config types.OTLPConfig, stats LiveStats) (*OTLP, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.ServiceName == "" {
+		config.ServiceName = "goload"
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 512
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	for name, value := range config.Headers {
+		header.Set(name, value)
+	}
+	o := &OTLP{
+		config:  config,
+		stats:   stats,
+		client:  &http.Client{Timeout: config.Timeout},
+		header:  header,
+		series:  newSeriesSet(config.Tags, config.Buckets),
+		started: time.Now(),
+		resource: otlpResource{Attributes: []otlpAttribute{
+			stringAttribute("service.name", config.ServiceName),
+		}},
+	}
+	o.batchOutput = startBatchOutput(config.BufferSize, config.Interval, o.handle, o.flush)
+	return o, nil
+}
+
+func (o *OTLP) handle(result metrics.Result) {
+	o.series.record(result)
+	if !o.config.Traces || result.TraceID == "" {
+		return
+	}
+	name := result.Name
+	if name == "" {
+		name = strings.TrimSpace(result.Method + " " + result.URL)
+	}
+	attributes := []otlpAttribute{
+		stringAttribute("http.request.method", result.Method),
+		stringAttribute("url.full", result.URL),
+		intAttribute("http.response.status_code", int64(result.Status)),
+		intAttribute("goload.vu", int64(result.VU)),
+		intAttribute("goload.iteration", int64(result.Iteration)),
+	}
+	for _, tag := range selectTags(o.config.Tags, result.Tags) {
+		attributes = append(attributes, stringAttribute(tag.name, tag.value))
+	}
+	status := otlpStatus{Code: otlpStatusOK}
+	if result.Failed {
+		status = otlpStatus{Code: otlpStatusFail, Message: result.Error}
+	}
+	o.spans = append(o.spans, otlpSpan{
+		TraceID:           result.TraceID,
+		SpanID:            result.SpanID,
+		Name:              name,
+		Kind:              otlpSpanClient,
+		StartTimeUnixNano: unixNano(result.Timestamp.Add(-result.Duration)),
+		EndTimeUnixNano:   unixNano(result.Timestamp),
+		Attributes:        attributes,
+		Status:            status,
+	})
+	if len(o.spans) >= o.config.BatchSize {
+		o.sendSpans()
+	}
+}
+
+func (o *OTLP) flush(now time.Time) {
+	o.sendSpans()
+	o.send("/v1/metrics", map[string]any{
+		"resourceMetrics": []map[string]any{{
+			"resource": o.resource,
+			"scopeMetrics": []map[string]any{{
+				"scope":   otlpScope{Name: "goload"},
+				"metrics": o.metrics(now),
+			}},
+		}},
+	})
+}
+
+func (o *OTLP) sendSpans() {
+	if len(o.spans) == 0 {
+		return
+	}
+	o.send("/v1/traces", map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": o.resource,
+			"scopeSpans": []map[string]any{{
+				"scope": otlpScope{Name: "goload"},
+				"spans": o.spans,
+			}},
+		}},
+	})
+	o.spans = nil
+}
+
+func (o *OTLP) send(path string, payload any) {
+	body, err := json.Marshal(payload)
+	if err == nil {
+		err = postWithRetries(o.client, o.config.Endpoint+path, o.header, body, retryCount(o.config.Retries))
+	}
+	if err != nil {
+		o.failed++
+		o.err = err
+	}
+}
+
+func (o *OTLP) metrics(now time.Time) []otlpMetric {
+	start, end := unixNano(o.started), unixNano(now)
+	gauge := func(name, description string, value int64) otlpMetric {
+		return otlpMetric{Name: name, Description: description, Unit: "1", Gauge: &otlpGauge{
+			DataPoints: []otlpNumberPoint{{TimeUnixNano: end, AsInt: strconv.FormatInt(value, 10)}},
+		}}
+	}
+	collected := []otlpMetric{
+		gauge("goload.vus.active", "Virtual users currently running.", o.stats.ActiveVUs()),
+		gauge("goload.requests.in_flight", "Requests currently being sent.", o.stats.InFlightRequests()),
+		gauge("goload.metric_queue.depth", "Metrics waiting for a metric worker.", int64(o.stats.QueueDepth())),
+	}
+
+	snapshot := o.series.snapshot()
+	counter := func(name, description, unit string, value func(requestSeries) int64) otlpMetric {
+		points := make([]otlpNumberPoint, len(snapshot))
+		for i, series := range snapshot {
+			points[i] = otlpNumberPoint{
+				Attributes:        attributes(series.labels),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				AsInt:             strconv.FormatInt(value(series), 10),
+			}
+		}
+		return otlpMetric{Name: name, Description: description, Unit: unit, Sum: &otlpSum{
+			DataPoints:             points,
+			AggregationTemporality: otlpCumulative,
+			IsMonotonic:            true,
+		}}
+	}
+	collected = append(collected,
+		counter("goload.requests", "Requests sent.", "1", func(s requestSeries) int64 { return s.requests }),
+		counter("goload.request.failures", "Requests that failed.", "1", func(s requestSeries) int64 { return s.failures }),
+		counter("goload.bytes.sent", "Bytes sent on the wire.", "By", func(s requestSeries) int64 { return s.bytesSent }),
+		counter("goload.bytes.received", "Bytes received on the wire.", "By", func(s requestSeries) int64 { return s.bytesRecv }),
+	)
+
+	points := make([]otlpHistogramPoint, len(snapshot))
+	for i, series := range snapshot {
+		counts := make([]string, len(series.buckets))
+		var total int64
+		for j, count := range series.buckets {
+			counts[j] = strconv.FormatInt(count, 10)
+			total += count
+		}
+		points[i] = otlpHistogramPoint{
+			Attributes:        attributes(series.labels),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             strconv.FormatInt(total, 10),
+			Sum:               series.sum,
+			BucketCounts:      counts,
+			ExplicitBounds:    o.series.buckets,
+		}
+	}
+	return append(collected, otlpMetric{
+		Name:        "goload.request.duration",
+		Description: "Request latency.",
+		Unit:        "s",
+		Histogram:   &otlpHistogram{DataPoints: points, AggregationTemporality: otlpCumulative},
+	})
+}
+
+// Close sends the last spans and the final values of the metrics. The fields
+// written by the goroutine are only read once it is over.
+func (o *OTLP) Close() error {
+	dropped := o.stop()
+	if o.err != nil {
+		return fmt.Errorf("%d otlp exports failed, last error: %s", o.failed, o.err)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%d results dropped, otlp could not keep up", dropped)
+	}
+	return nil
+}
+
+func attributes(labels []labelPair) []otlpAttribute {
+	converted := make([]otlpAttribute, len(labels))
+	for i, label := range labels {
+		converted[i] = stringAttribute(label.name, label.value)
+	}
+	return converted
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: strconv.FormatInt(value, 10)}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,156 @@
+package output
+
+import (
+	"encoding/json"
+	"goload/internal/metrics"
+	"goload/types"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// otlpRequests collects the decoded OTLP/HTTP JSON exports by path.
+type otlpRequests struct {
+	mu       sync.Mutex
+	payloads map[string][]map[string]any
+}
+
+func (o *otlpRequests) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := r.URL.Path + " " + r.Header.Get("Content-Type") + " " + r.Header.Get("Authorization")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.payloads[key] = append(o.payloads[key], payload)
+}
+
+// jsonPath walks the decoded JSON along keys and array indexes.
+func jsonPath(value any, steps ...any) any {
+	for _, step := range steps {
+		switch step := step.(type) {
+		case string:
+			object, _ := value.(map[string]any)
+			value = object[step]
+		case int:
+			array, _ := value.([]any)
+			if step >= len(array) {
+				return nil
+			}
+			value = array[step]
+		}
+	}
+	return value
+}
+
+func TestOTLPRoundTrip(t *testing.T) {
+	requests := &otlpRequests{payloads: map[string][]map[string]any{}}
+	server := httptest.NewServer(requests)
+	defer server.Close()
+
+	o, err := NewOTLP(types.OTLPConfig{
+		Endpoint: server.URL + "/",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+		Interval: time.Hour,
+		Tags:     []string{"test"},
+		Buckets:  []float64{0.1},
+		Traces:   true,
+	}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Unix(10, 0)
+	o.WriteResult(metrics.Result{
+		Timestamp: end,
+		Duration:  250 * time.Millisecond,
+		Method:    "GET",
+		URL:       "http://shop/cart",
+		Status:    503,
+		Failed:    true,
+		Error:     "503 Service Unavailable",
+		TraceID:   "0af7651916cd43dd8448eb211c80319c",
+		SpanID:    "b7ad6b7169203331",
+		Tags:      map[string]string{"test": "shop", "status": "503"},
+	})
+	o.WriteResult(metrics.Result{Duration: 50 * time.Millisecond, Tags: map[string]string{"test": "shop"}})
+	o.WriteResult(metrics.Result{NoLatency: true, Failed: true, Tags: map[string]string{"test": "shop"}})
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	traces := requests.payloads["/v1/traces application/json Bearer token"]
+	if len(traces) != 1 {
+		t.Fatalf("got %d trace exports: %v", len(traces), requests.payloads)
+	}
+	span := jsonPath(traces[0], "resourceSpans", 0, "scopeSpans", 0, "spans", 0)
+	for _, check := range []struct {
+		got, want any
+	}{
+		{jsonPath(traces[0], "resourceSpans", 0, "resource", "attributes", 0, "value", "stringValue"), "goload"},
+		{jsonPath(span, "traceId"), "0af7651916cd43dd8448eb211c80319c"},
+		{jsonPath(span, "spanId"), "b7ad6b7169203331"},
+		{jsonPath(span, "name"), "GET http://shop/cart"},
+		{jsonPath(span, "kind"), float64(otlpSpanClient)},
+		{jsonPath(span, "startTimeUnixNano"), "9750000000"},
+		{jsonPath(span, "endTimeUnixNano"), "10000000000"},
+		{jsonPath(span, "status", "code"), float64(otlpStatusFail)},
+		{jsonPath(span, "status", "message"), "503 Service Unavailable"},
+	} {
+		if check.got != check.want {
+			t.Errorf("got %v, want %v in %v", check.got, check.want, span)
+		}
+	}
+
+	exports := requests.payloads["/v1/metrics application/json Bearer token"]
+	if len(exports) != 1 {
+		t.Fatalf("got %d metric exports", len(exports))
+	}
+	byName := map[string]any{}
+	for _, metric := range jsonPath(exports[0], "resourceMetrics", 0, "scopeMetrics", 0, "metrics").([]any) {
+		byName[jsonPath(metric, "name").(string)] = metric
+	}
+	for _, check := range []struct {
+		got, want any
+	}{
+		{jsonPath(byName["goload.vus.active"], "gauge", "dataPoints", 0, "asInt"), "4"},
+		{jsonPath(byName["goload.requests"], "sum", "dataPoints", 0, "asInt"), "3"},
+		{jsonPath(byName["goload.requests"], "sum", "dataPoints", 0, "attributes", 0, "key"), "test"},
+		{jsonPath(byName["goload.requests"], "sum", "aggregationTemporality"), float64(otlpCumulative)},
+		{jsonPath(byName["goload.requests"], "sum", "isMonotonic"), true},
+		{jsonPath(byName["goload.request.failures"], "sum", "dataPoints", 0, "asInt"), "2"},
+		{jsonPath(byName["goload.request.duration"], "histogram", "dataPoints", 0, "count"), "2"},
+		{jsonPath(byName["goload.request.duration"], "histogram", "dataPoints", 0, "sum"), 0.3},
+		{jsonPath(byName["goload.request.duration"], "histogram", "dataPoints", 0, "bucketCounts", 0), "1"},
+		{jsonPath(byName["goload.request.duration"], "histogram", "dataPoints", 0, "bucketCounts", 1), "1"},
+		{jsonPath(byName["goload.request.duration"], "histogram", "dataPoints", 0, "explicitBounds", 0), 0.1},
+	} {
+		if check.got != check.want {
+			t.Errorf("got %v, want %v", check.got, check.want)
+		}
+	}
+}
+
+func TestOTLPRetriesDisabled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	o, err := NewOTLP(types.OTLPConfig{Endpoint: server.URL, Interval: time.Hour, Retries: new(int)}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err == nil {
+		t.Error("expected the failed export to be reported")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests", got)
+	}
+}
@@ -0,0 +1,134 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"goload/internal/metrics"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// LiveStats are the gauges read on every scrape.
+type LiveStats interface {
+	ActiveVUs() int64
+	InFlightRequests() int64
+	QueueDepth() int
+}
+
+// Prometheus serves the live request metrics in the Prometheus text format,
+// or in the OpenMetrics one when the scraper asks for it.
+type Prometheus struct {
+	server *http.Server
+	series *seriesSet
+	stats  LiveStats
+}
+
+// NewPrometheus listens on address and serves the metrics on path. labels
+// restricts the tags turned into labels and buckets overrides DefaultBuckets.
+func NewPrometheus(address, path string, labels []string, buckets []float64, stats LiveStats) (*Prometheus, error) {
+	if path == "" {
+		path = "/metrics"
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error starting the prometheus endpoint: %s", err)
+	}
+	p := &Prometheus{
+		series: newSeriesSet(labels, buckets),
+		stats:  stats,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, p.serveHTTP)
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+func (p *Prometheus) WriteResult(result metrics.Result) {
+	p.series.record(result)
+}
+
+// Close stops the endpoint.
+func (p *Prometheus) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.server.Shutdown(ctx)
+}
+
+func (p *Prometheus) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", prometheusContentType)
+	}
+	_, _ = w.Write([]byte(p.render(openMetrics)))
+}
+
+func (p *Prometheus) render(openMetrics bool) string {
+	var b strings.Builder
+	gauge := func(name, help string, value int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+	}
+	gauge("goload_active_vus", "Virtual users currently running.", p.stats.ActiveVUs())
+	gauge("goload_in_flight_requests", "Requests currently being sent.", p.stats.InFlightRequests())
+	gauge("goload_metric_queue_depth", "Metrics waiting for a metric worker.", int64(p.stats.QueueDepth()))
+
+	snapshot := p.series.snapshot()
+	counter := func(name, help string, value func(requestSeries) int64) {
+		family := name + "_total"
+		if openMetrics {
+			family = name
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", family, help, family)
+		for _, series := range snapshot {
+			fmt.Fprintf(&b, "%s_total%s %d\n", name, braces(series.key), value(series))
+		}
+	}
+	counter("goload_requests", "Requests sent.", func(s requestSeries) int64 { return s.requests })
+	counter("goload_request_failures", "Requests that failed.", func(s requestSeries) int64 { return s.failures })
+	counter("goload_bytes_sent", "Bytes sent on the wire.", func(s requestSeries) int64 { return s.bytesSent })
+	counter("goload_bytes_received", "Bytes received on the wire.", func(s requestSeries) int64 { return s.bytesRecv })
+
+	name := "goload_request_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Request latency.\n# TYPE %s histogram\n", name, name)
+	for _, series := range snapshot {
+		key := series.key
+		separator := ""
+		if key != "" {
+			separator = ","
+		}
+		var cumulative int64
+		for i, count := range series.buckets {
+			cumulative += count
+			le := "+Inf"
+			if i < len(p.series.buckets) {
+				le = strconv.FormatFloat(p.series.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s%sle=\"%s\"} %d\n", name, key, separator, le, cumulative)
+		}
+		fmt.Fprintf(&b, "%s_sum%s %s\n", name, braces(key), strconv.FormatFloat(series.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(key), cumulative)
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+	return b.String()
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
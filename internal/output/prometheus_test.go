@@ -0,0 +1,125 @@
+package output
+
+import (
+	"goload/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedStats struct{}
+
+func (fixedStats) ActiveVUs() int64        { return 4 }
+func (fixedStats) InFlightRequests() int64 { return 2 }
+func (fixedStats) QueueDepth() int         { return 0 }
+
+func testPrometheus() *Prometheus {
+	p := &Prometheus{series: newSeriesSet([]string{"test", "status", "dc-name"}, []float64{0.1, 1}), stats: fixedStats{}}
+	for _, result := range []metrics.Result{
+		{Duration: 50 * time.Millisecond, BytesSent: 10, BytesRecv: 100, Tags: map[string]string{"test": "shop", "status": "200", "dc-name": `eu "west"`}},
+		{Duration: 500 * time.Millisecond, BytesSent: 10, BytesRecv: 100, Tags: map[string]string{"test": "shop", "status": "200", "dc-name": `eu "west"`}},
+		{Failed: true, NoLatency: true, Tags: map[string]string{"test": "shop", "status": "error", "dc-name": `eu "west"`}},
+	} {
+		p.WriteResult(result)
+	}
+	return p
+}
+
+func TestPrometheusText(t *testing.T) {
+	want := `# HELP goload_active_vus Virtual users currently running.
+# TYPE goload_active_vus gauge
+goload_active_vus 4
+# HELP goload_in_flight_requests Requests currently being sent.
+# TYPE goload_in_flight_requests gauge
+goload_in_flight_requests 2
+# HELP goload_metric_queue_depth Metrics waiting for a metric worker.
+# TYPE goload_metric_queue_depth gauge
+goload_metric_queue_depth 0
+# HELP goload_requests_total Requests sent.
+# TYPE goload_requests_total counter
+goload_requests_total{dc_name="eu \"west\"",status="200",test="shop"} 2
+goload_requests_total{dc_name="eu \"west\"",status="error",test="shop"} 1
+# HELP goload_request_failures_total Requests that failed.
+# TYPE goload_request_failures_total counter
+goload_request_failures_total{dc_name="eu \"west\"",status="200",test="shop"} 0
+goload_request_failures_total{dc_name="eu \"west\"",status="error",test="shop"} 1
+# HELP goload_bytes_sent_total Bytes sent on the wire.
+# TYPE goload_bytes_sent_total counter
+goload_bytes_sent_total{dc_name="eu \"west\"",status="200",test="shop"} 20
+goload_bytes_sent_total{dc_name="eu \"west\"",status="error",test="shop"} 0
+# HELP goload_bytes_received_total Bytes received on the wire.
+# TYPE goload_bytes_received_total counter
+goload_bytes_received_total{dc_name="eu \"west\"",status="200",test="shop"} 200
+goload_bytes_received_total{dc_name="eu \"west\"",status="error",test="shop"} 0
+# HELP goload_request_duration_seconds Request latency.
+# TYPE goload_request_duration_seconds histogram
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="200",test="shop",le="0.1"} 1
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="200",test="shop",le="1"} 2
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="200",test="shop",le="+Inf"} 2
+goload_request_duration_seconds_sum{dc_name="eu \"west\"",status="200",test="shop"} 0.55
+goload_request_duration_seconds_count{dc_name="eu \"west\"",status="200",test="shop"} 2
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="error",test="shop",le="0.1"} 0
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="error",test="shop",le="1"} 0
+goload_request_duration_seconds_bucket{dc_name="eu \"west\"",status="error",test="shop",le="+Inf"} 0
+goload_request_duration_seconds_sum{dc_name="eu \"west\"",status="error",test="shop"} 0
+goload_request_duration_seconds_count{dc_name="eu \"west\"",status="error",test="shop"} 0
+`
+	if got := testPrometheus().render(false); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrometheusOpenMetrics(t *testing.T) {
+	p := testPrometheus()
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	p.serveHTTP(recorder, req)
+
+	body := recorder.Body.String()
+	if recorder.Header().Get("Content-Type") != openMetricsContentType {
+		t.Errorf("got content type %q", recorder.Header().Get("Content-Type"))
+	}
+	// Counter families drop the _total suffix their samples keep
+	for _, line := range []string{
+		"# TYPE goload_requests counter\n",
+		"# HELP goload_requests Requests sent.\n",
+		"goload_requests_total{dc_name=\"eu \\\"west\\\"\",status=\"200\",test=\"shop\"} 2\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q", line)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("missing # EOF")
+	}
+
+	recorder = httptest.NewRecorder()
+	p.serveHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Header().Get("Content-Type") != prometheusContentType || strings.Contains(recorder.Body.String(), "# EOF") {
+		t.Error("expected the text format by default")
+	}
+}
+
+func TestSeriesAreCapped(t *testing.T) {
+	series := newSeriesSet(nil, nil)
+	for i := 0; i < maxSeries+100; i++ {
+		series.record(metrics.Result{Duration: time.Millisecond, Tags: map[string]string{"test": "shop", "phase": "peak", "url": "/items/" + strconv.Itoa(i)}})
+	}
+	snapshot := series.snapshot()
+	if len(snapshot) != maxSeries+1 {
+		t.Fatalf("got %d series, want %d", len(snapshot), maxSeries+1)
+	}
+	for _, s := range snapshot {
+		if s.key == `phase="peak",test="shop",url="other"` {
+			if s.requests != 100 {
+				t.Errorf("got %d requests in the other series, want 100", s.requests)
+			}
+			return
+		}
+	}
+	t.Error("missing the other series")
+}
@@ -0,0 +1,204 @@
+package output
+
+import (
+	"fmt"
+	"github.com/klauspost/compress/snappy"
+	"goload/internal/metrics"
+	"goload/types"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RemoteWrite pushes the request metrics with the Prometheus remote write
+// protocol: snappy compressed WriteRequest protobufs. The series are
+// cumulative, as if they had been scraped at every interval.
+type RemoteWrite struct {
+	config types.RemoteWriteConfig
+	series *seriesSet
+	stats  LiveStats
+	client *http.Client
+	quit   chan struct{}
+	done   chan struct{}
+	mu     sync.Mutex
+	failed int
+	err    error
+}
+
+// remoteSeries is a time series with its single sample.
+type remoteSeries struct {
+	labels []labelPair
+	value  float64
+}
+
+func NewRemoteWrite(config types.RemoteWriteConfig, stats LiveStats) (*RemoteWrite, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 500
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	w := &RemoteWrite{
+		config: config,
+		series: newSeriesSet(config.Labels, config.Buckets),
+		stats:  stats,
+		client: &http.Client{Timeout: config.Timeout},
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *RemoteWrite) WriteResult(result metrics.Result) {
+	w.series.record(result)
+}
+
+// Close pushes the final values of the series.
+func (w *RemoteWrite) Close() error {
+	close(w.quit)
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return fmt.Errorf("%d remote write requests failed, last error: %s", w.failed, w.err)
+	}
+	return nil
+}
+
+func (w *RemoteWrite) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.push(now)
+		case <-w.quit:
+			w.push(time.Now())
+			return
+		}
+	}
+}
+
+func (w *RemoteWrite) push(now time.Time) {
+	series := w.collect()
+	timestamp := now.UnixMilli()
+	for start := 0; start < len(series); start += w.config.BatchSize {
+		end := min(start+w.config.BatchSize, len(series))
+		body := snappy.Encode(nil, encodeWriteRequest(series[start:end], timestamp))
+		if err := postWithRetries(w.client, w.config.URL, w.header(), body, retryCount(w.config.Retries)); err != nil {
+			w.mu.Lock()
+			w.failed++
+			w.err = err
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *RemoteWrite) header() http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-protobuf")
+	header.Set("Content-Encoding", "snappy")
+	header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for name, value := range w.config.Headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+// collect lists the gauges, then the counters and the histogram of every
+// label set, with the same names as the Prometheus endpoint.
+func (w *RemoteWrite) collect() []remoteSeries {
+	var series []remoteSeries
+	add := func(name string, labels []labelPair, value float64) {
+		all := make([]labelPair, 0, len(labels)+len(w.config.ExternalLabels)+1)
+		all = append(all, labelPair{name: "__name__", value: name})
+		all = append(all, labels...)
+		for labelName, labelValue := range w.config.ExternalLabels {
+			if !hasLabel(labels, labelName) {
+				all = append(all, labelPair{name: labelName, value: labelValue})
+			}
+		}
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].name < all[j].name
+		})
+		series = append(series, remoteSeries{labels: all, value: value})
+	}
+
+	add("goload_active_vus", nil, float64(w.stats.ActiveVUs()))
+	add("goload_in_flight_requests", nil, float64(w.stats.InFlightRequests()))
+	add("goload_metric_queue_depth", nil, float64(w.stats.QueueDepth()))
+	for _, s := range w.series.snapshot() {
+		add("goload_requests_total", s.labels, float64(s.requests))
+		add("goload_request_failures_total", s.labels, float64(s.failures))
+		add("goload_bytes_sent_total", s.labels, float64(s.bytesSent))
+		add("goload_bytes_received_total", s.labels, float64(s.bytesRecv))
+		var cumulative int64
+		for i, count := range s.buckets {
+			cumulative += count
+			le := "+Inf"
+			if i < len(w.series.buckets) {
+				le = strconv.FormatFloat(w.series.buckets[i], 'g', -1, 64)
+			}
+			add("goload_request_duration_seconds_bucket", append(s.labels[:len(s.labels):len(s.labels)], labelPair{name: "le", value: le}), float64(cumulative))
+		}
+		add("goload_request_duration_seconds_sum", s.labels, s.sum)
+		add("goload_request_duration_seconds_count", s.labels, float64(cumulative))
+	}
+	return series
+}
+
+// hasLabel tells whether a tag label has the name of an external label, which
+// the tag label takes precedence over, like in Prometheus.
+func hasLabel(labels []labelPair, name string) bool {
+	for _, label := range labels {
+		if label.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteSeries, timestamp int64) []byte {
+	var request []byte
+	for _, s := range series {
+		var ts []byte
+		for _, label := range s.labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.name)
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, encoded)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, ts)
+	}
+	return request
+}
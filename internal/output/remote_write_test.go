@@ -0,0 +1,178 @@
+package output
+
+import (
+	"github.com/klauspost/compress/snappy"
+	"goload/internal/metrics"
+	"goload/types"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// decodeWriteRequest decodes a snappy compressed WriteRequest into its series,
+// keyed by their sorted label pairs.
+func decodeWriteRequest(t *testing.T, body []byte) map[string]float64 {
+	t.Helper()
+	request, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := func(message []byte, visit func(number protowire.Number, typ protowire.Type, value []byte)) {
+		for len(message) > 0 {
+			number, typ, n := protowire.ConsumeTag(message)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			message = message[n:]
+			n = protowire.ConsumeFieldValue(number, typ, message)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			visit(number, typ, message[:n])
+			message = message[n:]
+		}
+	}
+	series := map[string]float64{}
+	fields(request, func(_ protowire.Number, _ protowire.Type, value []byte) {
+		timeseries, _ := protowire.ConsumeBytes(value)
+		var labels []string
+		var sample float64
+		fields(timeseries, func(number protowire.Number, _ protowire.Type, value []byte) {
+			message, _ := protowire.ConsumeBytes(value)
+			var name, labelValue string
+			fields(message, func(field protowire.Number, _ protowire.Type, value []byte) {
+				switch {
+				case number == 1 && field == 1:
+					name, _ = protowire.ConsumeString(value)
+				case number == 1 && field == 2:
+					labelValue, _ = protowire.ConsumeString(value)
+				case number == 2 && field == 1:
+					bits, _ := protowire.ConsumeFixed64(value)
+					sample = math.Float64frombits(bits)
+				}
+			})
+			if number == 1 {
+				labels = append(labels, name+"="+labelValue)
+			}
+		})
+		if !sort.StringsAreSorted(labels) {
+			t.Errorf("labels not sorted: %v", labels)
+		}
+		series[strings.Join(labels, ",")] = sample
+	})
+	return series
+}
+
+func TestRemoteWriteRoundTrip(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	w, err := NewRemoteWrite(types.RemoteWriteConfig{
+		URL:            server.URL,
+		Interval:       time.Hour,
+		ExternalLabels: map[string]string{"job": "nightly", "test": "ignored"},
+		Buckets:        []float64{0.1},
+	}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteResult(metrics.Result{Duration: 50 * time.Millisecond, BytesSent: 10, Tags: map[string]string{"test": "shop", "status": "200"}})
+	w.WriteResult(metrics.Result{Failed: true, NoLatency: true, Tags: map[string]string{"test": "shop", "status": "200"}})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("got %d requests", len(bodies))
+	}
+	series := decodeWriteRequest(t, bodies[0])
+	labels := "job=nightly,status=200,test=shop"
+	for name, want := range map[string]float64{
+		"__name__=goload_active_vus,job=nightly,test=ignored":                                      4,
+		"__name__=goload_requests_total," + labels:                                                 2,
+		"__name__=goload_request_failures_total," + labels:                                         1,
+		"__name__=goload_bytes_sent_total," + labels:                                               10,
+		"__name__=goload_request_duration_seconds_bucket,job=nightly,le=0.1,status=200,test=shop":  1,
+		"__name__=goload_request_duration_seconds_bucket,job=nightly,le=+Inf,status=200,test=shop": 1,
+		"__name__=goload_request_duration_seconds_count," + labels:                                 1,
+		"__name__=goload_request_duration_seconds_sum," + labels:                                   0.05,
+	} {
+		if got, ok := series[name]; !ok || got != want {
+			t.Errorf("%s: got %v (present %v), want %v", name, got, ok, want)
+		}
+	}
+	if len(series) != 3+4+2+2 {
+		t.Errorf("got %d series", len(series))
+	}
+}
+
+func TestRemoteWriteRetries(t *testing.T) {
+	if retryCount(nil) != 3 {
+		t.Errorf("got %d retries by default", retryCount(nil))
+	}
+	one := 1
+	for _, test := range []struct {
+		retries *int
+		want    int32
+	}{
+		{&one, 2},
+		{new(int), 1},
+	} {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		w, err := NewRemoteWrite(types.RemoteWriteConfig{URL: server.URL, Interval: time.Hour, Retries: test.retries}, fixedStats{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if err := w.Close(); err == nil {
+			t.Error("expected the failed request to be reported")
+		}
+		server.Close()
+		if got := requests.Load(); got != test.want {
+			t.Errorf("retries %v: got %d requests after %s, want %d", test.retries, got, time.Since(start), test.want)
+		}
+	}
+}
+
+func TestRemoteWriteExternalLabels(t *testing.T) {
+	for _, test := range []struct {
+		labels map[string]string
+		tags   []string
+		valid  bool
+	}{
+		{map[string]string{"job": "ci"}, []string{"test"}, true},
+		{map[string]string{"ci-job": "nightly"}, nil, false},
+		{map[string]string{"1job": "nightly"}, nil, false},
+		{map[string]string{"__name__": "x"}, nil, false},
+		{map[string]string{"le": "1"}, nil, false},
+		{map[string]string{"test": "x"}, []string{"test"}, false},
+		{map[string]string{"dc_name": "x"}, []string{"dc-name"}, false},
+	} {
+		config := types.RemoteWriteConfig{URL: "http://localhost:9090/api/v1/write", ExternalLabels: test.labels, Labels: test.tags}
+		if err := config.Validate(); (err == nil) != test.valid {
+			t.Errorf("%v with labels %v: got %v", test.labels, test.tags, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package output
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"goload/internal/metrics"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultResultsBuffer = 10000
+
+var resultsCSVHeader = []string{
+	"timestamp", "vu", "iteration", "test", "phase", "name", "method", "url", "protocol", "status", "failed",
+	"duration_ms", "dns_ms", "connect_ms", "tls_ms", "proxy_connect_ms", "first_byte_ms",
+	"bytes_sent", "bytes_recv", "error",
+}
+
+// ResultsWriter streams every request result to a JSON lines or CSV file.
+// Results are queued and written by a single goroutine; when the queue is
+// full they are dropped rather than slowing the VUs down, and counted.
+type ResultsWriter struct {
+	results chan metrics.Result
+	done    chan struct{}
+	dropped atomic.Int64
+	file    *os.File
+	gzip    *gzip.Writer
+	buffer  *bufio.Writer
+	csv     *csv.Writer
+	err     error
+}
+
+// NewResultsWriter creates the file at path, CSV when its extension is .csv
+// and JSON lines otherwise. A .gz extension, or compress, gzips the file.
+func NewResultsWriter(path string, bufferSize int, compress bool) (*ResultsWriter, error) {
+	if bufferSize <= 0 {
+		bufferSize = DefaultResultsBuffer
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating results file: %s", err)
+	}
+	w := &ResultsWriter{
+		results: make(chan metrics.Result, bufferSize),
+		done:    make(chan struct{}),
+		file:    file,
+	}
+
+	format := strings.ToLower(path)
+	var sink io.Writer = file
+	if strings.HasSuffix(format, ".gz") || compress {
+		w.gzip = gzip.NewWriter(file)
+		sink = w.gzip
+		format = strings.TrimSuffix(format, ".gz")
+	}
+	w.buffer = bufio.NewWriterSize(sink, 64*1024)
+	if filepath.Ext(format) == ".csv" {
+		w.csv = csv.NewWriter(w.buffer)
+		_ = w.csv.Write(resultsCSVHeader)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *ResultsWriter) WriteResult(result metrics.Result) {
+	select {
+	case w.results <- result:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+func (w *ResultsWriter) run() {
+	defer close(w.done)
+	encoder := json.NewEncoder(w.buffer)
+	for result := range w.results {
+		if w.err != nil {
+			continue
+		}
+		if w.csv != nil {
+			w.err = w.csv.Write(csvRecord(result))
+		} else {
+			w.err = encoder.Encode(jsonRecord(result))
+		}
+	}
+}
+
+// Close writes the queued results and closes the file.
+func (w *ResultsWriter) Close() error {
+	close(w.results)
+	<-w.done
+	err := w.err
+	if w.csv != nil {
+		w.csv.Flush()
+		if err == nil {
+			err = w.csv.Error()
+		}
+	}
+	if flushErr := w.buffer.Flush(); err == nil {
+		err = flushErr
+	}
+	if w.gzip != nil {
+		if closeErr := w.gzip.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing results: %s", err)
+	}
+	if dropped := w.dropped.Load(); dropped > 0 {
+		return fmt.Errorf("%d results dropped, the results file could not keep up", dropped)
+	}
+	return nil
+}
+
+type resultRecord struct {
+	Timestamp      time.Time         `json:"timestamp"`
+	VU             int               `json:"vu"`
+	Iteration      int               `json:"iteration"`
+	Test           string            `json:"test"`
+	Phase          string            `json:"phase"`
+	Name           string            `json:"name,omitempty"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Protocol       string            `json:"protocol,omitempty"`
+	Status         int               `json:"status"`
+	Failed         bool              `json:"failed"`
+	DurationMs     float64           `json:"duration_ms"`
+	DNSMs          float64           `json:"dns_ms,omitempty"`
+	ConnectMs      float64           `json:"connect_ms,omitempty"`
+	TLSMs          float64           `json:"tls_ms,omitempty"`
+	ProxyConnectMs float64           `json:"proxy_connect_ms,omitempty"`
+	FirstByteMs    float64           `json:"first_byte_ms,omitempty"`
+	BytesSent      int64             `json:"bytes_sent"`
+	BytesRecv      int64             `json:"bytes_recv"`
+	Error          string            `json:"error,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	TraceID        string            `json:"trace_id,omitempty"`
+	SpanID         string            `json:"span_id,omitempty"`
+}
+
+func jsonRecord(result metrics.Result) resultRecord {
+	return resultRecord{
+		Timestamp:      result.Timestamp,
+		VU:             result.VU,
+		Iteration:      result.Iteration,
+		Test:           result.Test,
+		Phase:          result.Phase,
+		Name:           result.Name,
+		Method:         result.Method,
+		URL:            result.URL,
+		Protocol:       result.Protocol,
+		Status:         result.Status,
+		Failed:         result.Failed,
+		DurationMs:     milliseconds(result.Duration),
+		DNSMs:          milliseconds(result.Timing.DNS),
+		ConnectMs:      milliseconds(result.Timing.Connect),
+		TLSMs:          milliseconds(result.Timing.TLS),
+		ProxyConnectMs: milliseconds(result.Timing.ProxyConnect),
+		FirstByteMs:    milliseconds(result.Timing.FirstByte),
+		BytesSent:      result.BytesSent,
+		BytesRecv:      result.BytesRecv,
+		Error:          result.Error,
+		Tags:           result.Tags,
+		TraceID:        result.TraceID,
+		SpanID:         result.SpanID,
+	}
+}
+
+func csvRecord(result metrics.Result) []string {
+	duration := func(value time.Duration) string {
+		return strconv.FormatFloat(milliseconds(value), 'f', 3, 64)
+	}
+	return []string{
+		result.Timestamp.Format(time.RFC3339Nano),
+		strconv.Itoa(result.VU),
+		strconv.Itoa(result.Iteration),
+		result.Test,
+		result.Phase,
+		result.Name,
+		result.Method,
+		result.URL,
+		result.Protocol,
+		strconv.Itoa(result.Status),
+		strconv.FormatBool(result.Failed),
+		duration(result.Duration),
+		duration(result.Timing.DNS),
+		duration(result.Timing.Connect),
+		duration(result.Timing.TLS),
+		duration(result.Timing.ProxyConnect),
+		duration(result.Timing.FirstByte),
+		strconv.FormatInt(result.BytesSent, 10),
+		strconv.FormatInt(result.BytesRecv, 10),
+		result.Error,
+	}
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"goload/internal/metrics"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResult() metrics.Result {
+	return metrics.Result{
+		Timestamp: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		VU:        3,
+		Iteration: 7,
+		Test:      "shop",
+		Phase:     "peak",
+		Name:      "checkout",
+		Method:    "POST",
+		URL:       "https://shop.example.com/checkout",
+		Status:    201,
+		Duration:  1500 * time.Microsecond,
+		BytesSent: 120,
+		BytesRecv: 512,
+		Tags:      map[string]string{"test": "shop", "phase": "peak", "tier": "gold"},
+	}
+}
+
+func TestResultsWriterJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl.gz")
+	writer, err := NewResultsWriter(path, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.WriteResult(testResult())
+	failed := testResult()
+	failed.Failed, failed.Status, failed.Error = true, 0, "connection refused"
+	writer.WriteResult(failed)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(reader)
+	var records []resultRecord
+	for decoder.More() {
+		var record resultRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].DurationMs != 1.5 || records[0].Tags["tier"] != "gold" || records[0].Status != 201 {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+	if !records[1].Failed || records[1].Error != "connection refused" {
+		t.Errorf("unexpected record %+v", records[1])
+	}
+}
+
+func TestResultsWriterCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	writer, err := NewResultsWriter(path, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.WriteResult(testResult())
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	want := "2026-03-01T10:00:00Z,3,7,shop,peak,checkout,POST,https://shop.example.com/checkout,,201,false,1.500,0.000,0.000,0.000,0.000,0.000,120,512,"
+	if len(lines) != 2 || lines[0] != strings.Join(resultsCSVHeader, ",") || lines[1] != want {
+		t.Errorf("unexpected csv\n%s", content)
+	}
+}
+
+func TestResultsWriterCountsDroppedResults(t *testing.T) {
+	writer := &ResultsWriter{results: make(chan metrics.Result, 1)}
+	writer.WriteResult(testResult())
+	writer.WriteResult(testResult())
+	if writer.dropped.Load() != 1 {
+		t.Errorf("got %d dropped results, want 1", writer.dropped.Load())
+	}
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"goload/internal/metrics"
+	"goload/types"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histogram.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+// requestSeries accumulates the results sharing a label set.
+type requestSeries struct {
+	key       string // Rendered label pairs, without braces
+	labels    []labelPair
+	requests  int64
+	failures  int64
+	bytesSent int64
+	bytesRecv int64
+	buckets   []int64 // Not cumulative, the last one is +Inf
+	sum       float64
+}
+
+// seriesSet aggregates the results by label set for the Prometheus outputs.
+type seriesSet struct {
+	labels  []string // Tags kept as labels, every tag when empty
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*requestSeries
+}
+
+func newSeriesSet(labels []string, buckets []float64) *seriesSet {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &seriesSet{
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*requestSeries),
+	}
+}
+
+func (s *seriesSet) record(result metrics.Result) {
+	labels := s.labelPairs(result.Tags)
+	key := renderLabels(labels)
+	seconds := result.Duration.Seconds()
+	bucket := sort.SearchFloat64s(s.buckets, seconds)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	series, ok := s.series[key]
+	if !ok && len(s.series) >= maxSeries {
+		labels = overflowLabels(labels)
+		key = renderLabels(labels)
+		series, ok = s.series[key]
+	}
+	if !ok {
+		series = &requestSeries{key: key, labels: labels, buckets: make([]int64, len(s.buckets)+1)}
+		s.series[key] = series
+	}
+	series.requests++
+	if result.Failed {
+		series.failures++
+	}
+	series.bytesSent += result.BytesSent
+	series.bytesRecv += result.BytesRecv
+	if !result.NoLatency {
+		series.buckets[bucket]++
+		series.sum += seconds
+	}
+}
+
+// snapshot copies the series, sorted by label set.
+func (s *seriesSet) snapshot() []requestSeries {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make([]requestSeries, 0, len(s.series))
+	for _, series := range s.series {
+		copied := *series
+		copied.buckets = append([]int64(nil), series.buckets...)
+		snapshot = append(snapshot, copied)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].key < snapshot[j].key
+	})
+	return snapshot
+}
+
+func (s *seriesSet) labelPairs(tags map[string]string) []labelPair {
+	pairs := selectTags(s.labels, tags)
+	for i := range pairs {
+		pairs[i].name = types.LabelName(pairs[i].name)
+	}
+	return pairs
+}
+
+func renderLabels(labels []labelPair) string {
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = label.name + `="` + labelValue(label.value) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func labelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
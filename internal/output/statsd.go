@@ -0,0 +1,157 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"goload/internal/metrics"
+	"goload/types"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsD sends the request metrics to a StatsD agent over UDP, either the
+// metrics of every request or aggregates at every interval, plus the VU
+// gauges at every interval. With DogStatsD, the metric tags are sent along.
+type StatsD struct {
+	*batchOutput
+	config     types.StatsDConfig
+	stats      LiveStats
+	conn       net.Conn
+	aggregates *aggregates
+	packet     bytes.Buffer
+	failed     int
+	err        error
+}
+
+func NewStatsD(config types.StatsDConfig, stats LiveStats) (*StatsD, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Prefix == "" {
+		config.Prefix = "goload."
+	}
+	if config.Mode == "" {
+		config.Mode = IntervalMode
+	}
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.MaxPacketSize == 0 {
+		config.MaxPacketSize = 1432
+	}
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to statsd: %s", err)
+	}
+	o := &StatsD{
+		config:     config,
+		stats:      stats,
+		conn:       conn,
+		aggregates: newAggregates(config.Tags),
+	}
+	o.batchOutput = startBatchOutput(config.BufferSize, config.Interval, o.handle, o.flush)
+	return o, nil
+}
+
+func (o *StatsD) handle(result metrics.Result) {
+	if o.config.Mode == IntervalMode {
+		if !o.config.DogStatsD {
+			// Plain StatsD has no tags, the gauges of the tag sets would
+			// overwrite each other: every request goes in a single aggregate
+			result.Tags = nil
+		}
+		o.aggregates.record(result)
+		return
+	}
+	tags := o.tags(selectTags(o.config.Tags, result.Tags))
+	o.add("requests", "1", "c", tags)
+	if result.Failed {
+		o.add("request.failures", "1", "c", tags)
+	}
+	if !result.NoLatency {
+		o.add("request.duration", formatFloat(milliseconds(result.Duration)), "ms", tags)
+	}
+	o.add("bytes.sent", strconv.FormatInt(result.BytesSent, 10), "c", tags)
+	o.add("bytes.received", strconv.FormatInt(result.BytesRecv, 10), "c", tags)
+}
+
+func (o *StatsD) flush(time.Time) {
+	for _, group := range o.aggregates.take() {
+		tags := o.tags(group.tags)
+		histogram := group.histogram
+		o.add("requests", strconv.FormatInt(group.requests, 10), "c", tags)
+		o.add("request.failures", strconv.FormatInt(group.failures, 10), "c", tags)
+		o.add("bytes.sent", strconv.FormatInt(group.bytesSent, 10), "c", tags)
+		o.add("bytes.received", strconv.FormatInt(group.bytesRecv, 10), "c", tags)
+		o.add("request.duration.mean", formatFloat(histogram.Mean()/1000), "g", tags)
+		o.add("request.duration.p50", formatFloat(microsToMillis(histogram.ValueAtQuantile(50))), "g", tags)
+		o.add("request.duration.p90", formatFloat(microsToMillis(histogram.ValueAtQuantile(90))), "g", tags)
+		o.add("request.duration.p95", formatFloat(microsToMillis(histogram.ValueAtQuantile(95))), "g", tags)
+		o.add("request.duration.p99", formatFloat(microsToMillis(histogram.ValueAtQuantile(99))), "g", tags)
+		o.add("request.duration.max", formatFloat(microsToMillis(histogram.Max())), "g", tags)
+	}
+	o.add("active_vus", strconv.FormatInt(o.stats.ActiveVUs(), 10), "g", "")
+	o.add("in_flight_requests", strconv.FormatInt(o.stats.InFlightRequests(), 10), "g", "")
+	o.add("metric_queue_depth", strconv.Itoa(o.stats.QueueDepth()), "g", "")
+	o.send()
+}
+
+// tags renders the DogStatsD tags suffix, empty for plain StatsD.
+func (o *StatsD) tags(tags []labelPair) string {
+	if !o.config.DogStatsD || len(tags) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag.value != "" {
+			pairs = append(pairs, statsdEscaper.Replace(tag.name)+":"+statsdEscaper.Replace(tag.value))
+		}
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "|#" + strings.Join(pairs, ",")
+}
+
+// add appends a metric to the packet, sending the packet first when the
+// metric would not fit in it.
+func (o *StatsD) add(name, value, metricType, tags string) {
+	line := o.config.Prefix + name + ":" + value + "|" + metricType + tags
+	if o.packet.Len() > 0 && o.packet.Len()+1+len(line) > o.config.MaxPacketSize {
+		o.send()
+	}
+	if o.packet.Len() > 0 {
+		o.packet.WriteByte('\n')
+	}
+	o.packet.WriteString(line)
+}
+
+func (o *StatsD) send() {
+	if o.packet.Len() == 0 {
+		return
+	}
+	if _, err := o.conn.Write(o.packet.Bytes()); err != nil {
+		o.failed++
+		o.err = err
+	}
+	o.packet.Reset()
+}
+
+// Close sends the last metrics. The fields written by the goroutine are only
+// read once it is over.
+func (o *StatsD) Close() error {
+	dropped := o.stop()
+	_ = o.conn.Close()
+	if o.err != nil {
+		return fmt.Errorf("%d statsd packets failed, last error: %s", o.failed, o.err)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%d results dropped, statsd could not keep up", dropped)
+	}
+	return nil
+}
+
+// statsdEscaper replaces the separators of the DogStatsD format in tags.
+var statsdEscaper = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
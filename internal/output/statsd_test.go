@@ -0,0 +1,128 @@
+package output
+
+import (
+	"goload/internal/metrics"
+	"goload/types"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenStatsD returns the address of a UDP listener and a function reading
+// the packets received so far.
+func listenStatsD(t *testing.T) (string, func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn.LocalAddr().String(), func() []string {
+		var packets []string
+		buffer := make([]byte, 65536)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return packets
+			}
+			packets = append(packets, string(buffer[:n]))
+		}
+	}
+}
+
+func TestStatsDPlainIntervalIgnoresTags(t *testing.T) {
+	address, read := listenStatsD(t)
+	o, err := NewStatsD(types.StatsDConfig{Address: address, Interval: time.Hour}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.WriteResult(metrics.Result{Duration: 10 * time.Millisecond, Tags: map[string]string{"status": "200"}})
+	o.WriteResult(metrics.Result{Duration: 30 * time.Millisecond, Failed: true, Tags: map[string]string{"status": "500"}})
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.Join(read(), "\n"), "\n")
+	for _, want := range []string{
+		"goload.requests:2|c",
+		"goload.request.failures:1|c",
+		"goload.request.duration.max:30.015|g",
+		"goload.active_vus:4|g",
+	} {
+		if count := countLines(lines, want); count != 1 {
+			t.Errorf("got %q %d times in %v", want, count, lines)
+		}
+	}
+}
+
+func TestStatsDDogStatsDIntervalKeepsTags(t *testing.T) {
+	address, read := listenStatsD(t)
+	o, err := NewStatsD(types.StatsDConfig{Address: address, DogStatsD: true, Interval: time.Hour}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.WriteResult(metrics.Result{Tags: map[string]string{"status": "200", "name": "a|b,c"}})
+	o.WriteResult(metrics.Result{Tags: map[string]string{"status": "500"}})
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.Join(read(), "\n"), "\n")
+	for _, want := range []string{
+		"goload.requests:1|c|#name:a_b_c,status:200",
+		"goload.requests:1|c|#status:500",
+	} {
+		if countLines(lines, want) != 1 {
+			t.Errorf("missing %q in %v", want, lines)
+		}
+	}
+}
+
+func TestStatsDSplitsPackets(t *testing.T) {
+	address, read := listenStatsD(t)
+	o, err := NewStatsD(types.StatsDConfig{Address: address, Mode: RequestMode, Interval: time.Hour, MaxPacketSize: 64}, fixedStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		o.WriteResult(metrics.Result{Duration: time.Millisecond, BytesSent: 100, BytesRecv: 1000})
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := read()
+	if len(packets) < 2 {
+		t.Fatalf("got %d packets", len(packets))
+	}
+	var lines []string
+	for _, packet := range packets {
+		if len(packet) > 64 {
+			t.Errorf("packet of %d bytes: %q", len(packet), packet)
+		}
+		lines = append(lines, strings.Split(packet, "\n")...)
+	}
+	if count := countLines(lines, "goload.requests:1|c"); count != 10 {
+		t.Errorf("got %d request lines in %v", count, lines)
+	}
+	if count := countLines(lines, "goload.request.duration:1|ms"); count != 10 {
+		t.Errorf("got %d duration lines", count)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "goload.") || !strings.Contains(line, "|") {
+			t.Errorf("split line %q", line)
+		}
+	}
+}
+
+func countLines(lines []string, want string) int {
+	count := 0
+	for _, line := range lines {
+		if line == want {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"goload/types"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket shared by the VUs sending the requests it limits.
+// A request that finds the bucket empty reserves the next token, so waiting
+// VUs are served in turn.
+type Bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait for it.
+func (b *Bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available and returns the time waited.
+func (b *Bucket) Wait(ctx context.Context) (time.Duration, error) {
+	wait := b.reserve()
+	if wait == 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return wait, ctx.Err()
+	}
+}
+
+// Registry holds the buckets of a run, one per rate limit key.
+type Registry struct {
+	buckets map[string]*Bucket
+	mu      sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{buckets: make(map[string]*Bucket)}
+}
+
+// Wait applies the rate limit of request, if any. The returned metric is nil
+// for requests without a rate limit.
+func (r *Registry) Wait(ctx context.Context, request types.HTTPRequest) (*types.RateLimitMetric, error) {
+	limit := request.RateLimit
+	if limit == nil {
+		return nil, nil
+	}
+	if limit.Rate <= 0 {
+		return nil, fmt.Errorf("rate limit requires a positive rate")
+	}
+	key := Key(request)
+
+	r.mu.Lock()
+	bucket, ok := r.buckets[key]
+	if !ok {
+		// The first request of a key sets the rate of its bucket
+		bucket = NewBucket(limit.Rate, limit.Burst)
+		r.buckets[key] = bucket
+	}
+	r.mu.Unlock()
+
+	wait, err := bucket.Wait(ctx)
+	return &types.RateLimitMetric{Key: key, Wait: wait, Throttled: wait > 0}, err
+}
+
+// Key returns the key of the bucket request is limited by: its name or host.
+func Key(request types.HTTPRequest) string {
+	if request.RateLimit.By != "host" && request.Name != "" {
+		return "name:" + request.Name
+	}
+	return "host:" + host(request)
+}
+
+func host(request types.HTTPRequest) string {
+	var address string
+	switch {
+	case request.Kind == types.GRPCKind && request.GRPC != nil:
+		address = request.GRPC.Target
+	case request.Kind == types.TCPKind && request.TCP != nil:
+		address = request.TCP.Address
+	case request.Kind == types.UDPKind && request.UDP != nil:
+		address = request.UDP.Address
+	default:
+		parsedURL, err := url.Parse(request.URI)
+		if err != nil {
+			return request.URI
+		}
+		return parsedURL.Hostname()
+	}
+	if hostname, _, err := net.SplitHostPort(address); err == nil {
+		return hostname
+	}
+	return address
+}
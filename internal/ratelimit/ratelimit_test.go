@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"goload/types"
+	"testing"
+	"time"
+)
+
+func TestBucketSpacesRequestsAfterTheBurst(t *testing.T) {
+	bucket := NewBucket(20, 2)
+	start := time.Now()
+	var waits []time.Duration
+	for i := 0; i < 4; i++ {
+		wait, err := bucket.Wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		waits = append(waits, wait)
+	}
+	if waits[0] != 0 || waits[1] != 0 || waits[2] <= 0 || waits[3] <= 0 {
+		t.Errorf("unexpected waits %v", waits)
+	}
+	// Two tokens at 20 per second after the burst
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("4 requests took %s, want about 100ms", elapsed)
+	}
+}
+
+func TestBucketWaitIsCancelled(t *testing.T) {
+	bucket := NewBucket(1, 1)
+	_, _ = bucket.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := bucket.Wait(ctx); err == nil {
+		t.Error("expected the wait to be cancelled")
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		request types.HTTPRequest
+		key     string
+	}{
+		{types.HTTPRequest{Name: "login", URI: "https://api.example.com/login", RateLimit: &types.RateLimit{Rate: 1}}, "name:login"},
+		{types.HTTPRequest{Name: "login", URI: "https://api.example.com:8443/login", RateLimit: &types.RateLimit{Rate: 1, By: "host"}}, "host:api.example.com"},
+		{types.HTTPRequest{URI: "https://api.example.com/orders", RateLimit: &types.RateLimit{Rate: 1}}, "host:api.example.com"},
+		{types.HTTPRequest{Kind: types.GRPCKind, GRPC: &types.GRPCRequest{Target: "orders:50051"}, RateLimit: &types.RateLimit{Rate: 1}}, "host:orders"},
+		{types.HTTPRequest{Kind: types.TCPKind, TCP: &types.TCPRequest{Address: "cache:6379"}, RateLimit: &types.RateLimit{Rate: 1}}, "host:cache"},
+	}
+	for _, test := range tests {
+		if key := Key(test.request); key != test.key {
+			t.Errorf("got %s, want %s", key, test.key)
+		}
+	}
+}
+
+func TestRegistrySharesBucketsByKey(t *testing.T) {
+	registry := NewRegistry()
+	request := types.HTTPRequest{Name: "search", RateLimit: &types.RateLimit{Rate: 1000, Burst: 1}}
+	if metric, err := registry.Wait(context.Background(), request); err != nil || metric.Throttled {
+		t.Fatalf("first request: %+v, %v", metric, err)
+	}
+	metric, err := registry.Wait(context.Background(), request)
+	if err != nil || !metric.Throttled || metric.Key != "name:search" {
+		t.Errorf("second request: %+v, %v", metric, err)
+	}
+
+	if metric, err := registry.Wait(context.Background(), types.HTTPRequest{Name: "other"}); metric != nil || err != nil {
+		t.Errorf("unlimited request: %+v, %v", metric, err)
+	}
+	if _, err := registry.Wait(context.Background(), types.HTTPRequest{RateLimit: &types.RateLimit{}}); err == nil {
+		t.Error("expected an error for a zero rate")
+	}
+}
@@ -0,0 +1,206 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"goload/internal/metrics"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"float":    formatFloat,
+	"bytes":    formatBytes,
+	"tags":     formatTags,
+	"duration": formatDuration,
+	"time": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+	"add": func(a, b int64) int64 {
+		return a + b
+	},
+	"pct": func(part, total int64) string {
+		if total == 0 {
+			return "-"
+		}
+		return formatFloat(float64(part)/float64(total)*100) + "%"
+	},
+}).Parse(reportTemplate))
+
+// view is what the template renders: the summary and the charts drawn from
+// its time series.
+type view struct {
+	metrics.Summary
+	Charts []chart
+}
+
+// Write renders the HTML report of summary. The report is a single file with
+// no external resource, its charts are inline SVG.
+func Write(output io.Writer, summary metrics.Summary) error {
+	return page.Execute(output, view{Summary: summary, Charts: charts(summary.Timeseries)})
+}
+
+// WriteFile writes the HTML report of summary to path.
+func WriteFile(path string, summary metrics.Summary) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing report %s: %s", path, err)
+	}
+	err = Write(file, summary)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing report %s: %s", path, err)
+	}
+	return nil
+}
+
+// Generate regenerates the report of a run from its JSON summary.
+func Generate(summaryPath, reportPath string) error {
+	summary, err := metrics.ReadSummary(summaryPath)
+	if err != nil {
+		return err
+	}
+	return WriteFile(reportPath, summary)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func formatBytes(value int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	size := float64(value)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", value)
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+func formatTags(tags map[string]string, names []string) []string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = tags[name]
+	}
+	return values
+}
+
+func formatDuration(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
+
+const (
+	chartWidth   = 720
+	chartHeight  = 180
+	chartPadding = 40
+)
+
+var chartColors = []string{"#2563eb", "#f59e0b", "#dc2626"}
+
+type chart struct {
+	Title  string
+	Unit   string
+	Width  int
+	Height int
+	Left   int // Plot area, inside the axes
+	Top    int
+	Right  int
+	Bottom int
+	YMax   string
+	Start  string
+	End    string
+	Lines  []chartLine
+}
+
+type chartLine struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+// charts draws the RPS, the active VUs, the latency and the error rate of
+// the intervals.
+func charts(intervals []metrics.Interval) []chart {
+	if len(intervals) == 0 {
+		return nil
+	}
+	values := func(value func(metrics.Interval) float64) []float64 {
+		series := make([]float64, len(intervals))
+		for i, interval := range intervals {
+			series[i] = value(interval)
+		}
+		return series
+	}
+	start := intervals[0].Start.Format("15:04:05")
+	end := intervals[len(intervals)-1].Start.Format("15:04:05")
+	return []chart{
+		newChart("Requests per second", "rps", start, end, map[string][]float64{
+			"rps": values(func(i metrics.Interval) float64 { return i.RPS }),
+		}, []string{"rps"}),
+		newChart("Active VUs", "VUs", start, end, map[string][]float64{
+			"VUs": values(func(i metrics.Interval) float64 { return float64(i.ActiveVUs) }),
+		}, []string{"VUs"}),
+		newChart("Latency", "ms", start, end, map[string][]float64{
+			"p50": values(func(i metrics.Interval) float64 { return float64(i.P50) }),
+			"p95": values(func(i metrics.Interval) float64 { return float64(i.P95) }),
+			"p99": values(func(i metrics.Interval) float64 { return float64(i.P99) }),
+		}, []string{"p50", "p95", "p99"}),
+		newChart("Error rate", "%", start, end, map[string][]float64{
+			"errors": values(func(i metrics.Interval) float64 { return i.ErrorRate * 100 }),
+		}, []string{"errors"}),
+	}
+}
+
+func newChart(title, unit, start, end string, series map[string][]float64, names []string) chart {
+	c := chart{
+		Title:  title,
+		Unit:   unit,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartPadding,
+		Top:    10,
+		Right:  chartWidth - 10,
+		Bottom: chartHeight - 20,
+		Start:  start,
+		End:    end,
+	}
+	yMax := 0.0
+	for _, values := range series {
+		for _, value := range values {
+			yMax = max(yMax, value)
+		}
+	}
+	if yMax == 0 {
+		yMax = 1
+	}
+	c.YMax = formatFloat(yMax)
+
+	plotWidth, plotHeight := float64(c.Right-c.Left), float64(c.Bottom-c.Top)
+	for i, name := range names {
+		values := series[name]
+		points := make([]string, len(values))
+		for j, value := range values {
+			x := float64(c.Left)
+			if len(values) > 1 {
+				x += float64(j) / float64(len(values)-1) * plotWidth
+			}
+			y := float64(c.Bottom) - value/yMax*plotHeight
+			points[j] = strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+		}
+		c.Lines = append(c.Lines, chartLine{Name: name, Color: chartColors[i%len(chartColors)], Points: strings.Join(points, " ")})
+	}
+	return c
+}
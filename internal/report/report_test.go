@@ -0,0 +1,110 @@
+package report
+
+import (
+	"bytes"
+	"goload/internal/metrics"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSummary() metrics.Summary {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	return metrics.Summary{
+		SchemaVersion: metrics.SummarySchemaVersion,
+		Collection:    "shop",
+		StartedAt:     start,
+		EndedAt:       start.Add(2 * time.Second),
+		Requests:      metrics.RequestSummary{Total: 30, Successes: 26, Fails: 4},
+		Thresholds: []metrics.ThresholdResult{
+			{Test: "checkout", Kind: "pass_if", Metric: "p95", Target: "< 300", Value: 120, Passed: true},
+			{Test: "checkout", Kind: "fail_if", Metric: "error_rate", Target: "> 0.1", Value: 13.33, Error: "error_rate is 13.33"},
+		},
+		Tests: []metrics.TestSummary{{Name: "checkout", TestStats: metrics.TestStats{Requests: metrics.RequestSummary{Total: 30}}}},
+		Groups: []metrics.GroupSummary{{
+			By: []string{"name", "status"},
+			Rows: []metrics.GroupRow{{
+				Tags:     map[string]string{"name": "get-cart", "status": "200", "test": "checkout"},
+				Requests: metrics.RequestSummary{Total: 26, Successes: 26},
+				Latency:  metrics.LatencySummary{P50: 42, P99: 180},
+			}},
+		}},
+		Errors: []metrics.ErrorSummary{
+			{Error: "timeout", Count: 3},
+			{Error: "unexpected <html> body", Count: 1},
+		},
+		Timeseries: []metrics.Interval{
+			{Start: start, RPS: 10, ActiveVUs: 2, P50: 40, P95: 90, P99: 100, ErrorRate: 0.5},
+			{Start: start.Add(time.Second), RPS: 20, ActiveVUs: 4, P50: 50, P95: 80, P99: 200},
+		},
+	}
+}
+
+func render(t *testing.T, summary metrics.Summary) string {
+	var output bytes.Buffer
+	if err := Write(&output, summary); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestReport(t *testing.T) {
+	html := render(t, testSummary())
+	for _, want := range []string{
+		`<span class="verdict failed">FAILED</span>`,
+		// Thresholds
+		`<tr><td>checkout</td><td>pass_if</td><td>p95</td><td>&lt; 300</td><td class="num">120.00</td><td>passed</td></tr>`,
+		`<tr class="fail"><td>checkout</td><td>fail_if</td><td>error_rate</td><td>&gt; 0.1</td><td class="num">13.33</td><td>failed: error_rate is 13.33</td></tr>`,
+		// Groups, with the tags of the breakdown only
+		`<h3>By name, status</h3>`,
+		`<tr><th>name</th><th>status</th><th>Requests</th>`,
+		`<tr><td>get-cart</td><td>200</td><td class="num">26</td>`,
+		// Charts, scaled to the largest value of each
+		`<strong>Requests per second</strong>`,
+		`points="40.0,85.0 710.0,10.0"`,
+		`<strong>Latency</strong>`,
+		`<text x="40" y="10" dx="-4" dy="4" font-size="10" text-anchor="end">200.00</text>`,
+		`<strong>Error rate</strong>`,
+		`points="40.0,10.0 710.0,160.0"`,
+		`<text x="40" y="180" dy="-4" font-size="10">10:00:00</text>`,
+		`<text x="710" y="180" dy="-4" font-size="10" text-anchor="end">10:00:01</text>`,
+		// Errors, as a share of the failures
+		`<tr><td>timeout</td><td class="num">3</td><td class="num">75.00%</td></tr>`,
+		`<tr><td>unexpected &lt;html&gt; body</td><td class="num">1</td><td class="num">25.00%</td></tr>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Count(html, "<polyline") != 6 {
+		t.Errorf("got %d lines, want 6", strings.Count(html, "<polyline"))
+	}
+}
+
+func TestReportWithoutOptionalSections(t *testing.T) {
+	summary := metrics.Summary{SchemaVersion: metrics.SummarySchemaVersion, Collection: "shop", Passed: true}
+	html := render(t, summary)
+	for _, section := range []string{"<h2>Thresholds</h2>", "<h2>Over time</h2>", "<h2>Checks</h2>", "<h2>Errors</h2>", "<svg"} {
+		if strings.Contains(html, section) {
+			t.Errorf("unexpected %s", section)
+		}
+	}
+	if !strings.Contains(html, `<span class="verdict passed">PASSED</span>`) {
+		t.Error("missing the verdict")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	summaryPath := filepath.Join(dir, "summary.json")
+	if err := testSummary().WriteJSONFile(summaryPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(summaryPath, filepath.Join(dir, "report.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(filepath.Join(dir, "missing.json"), filepath.Join(dir, "report.html")); err == nil {
+		t.Error("expected an error for a missing summary")
+	}
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"fmt"
+	"goload/internal/client"
+	"goload/types"
+	"net/http"
+	"regexp"
+)
+
+// Checks evaluates the CheckCondition of a test against its responses.
+type Checks struct {
+	condition *CheckCondition
+	bodyRegex *regexp.Regexp
+}
+
+func NewChecks(condition *CheckCondition) (*Checks, error) {
+	if condition == nil {
+		return nil, nil
+	}
+	checks := &Checks{condition: condition}
+	if condition.BodyRegex != "" {
+		bodyRegex, err := regexp.Compile(condition.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body_regex: %s", err)
+		}
+		checks.bodyRegex = bodyRegex
+	}
+	return checks, nil
+}
+
+// NeedsFullBody tells whether the responses must be kept entirely in memory
+// for the checks to be evaluated.
+func (c *Checks) NeedsFullBody() bool {
+	return c != nil && c.bodyRegex != nil
+}
+
+// checkBodyMode rejects a response_body mode that would truncate the bodies
+// the checks need.
+func (c *Checks) checkBodyMode(mode types.BodyMode) error {
+	if c.NeedsFullBody() && mode != "" && mode != types.FullBody {
+		return fmt.Errorf("body_regex needs the full body, response_body mode is %s", mode)
+	}
+	return nil
+}
+
+// requestChecks holds the state of the checks of a single request.
+type requestChecks struct {
+	checks *Checks
+	body   *client.ContainsCheck
+}
+
+func (c *Checks) forRequest() *requestChecks {
+	if c == nil {
+		return nil
+	}
+	request := &requestChecks{checks: c}
+	if c.condition.Body != "" {
+		request.body = client.NewContainsCheck(c.condition.Body)
+	}
+	return request
+}
+
+// bodyChecks returns the checks to stream the response body through.
+func (r *requestChecks) bodyChecks() []client.BodyCheck {
+	if r == nil || r.body == nil {
+		return nil
+	}
+	return []client.BodyCheck{r.body}
+}
+
+func (r *requestChecks) evaluate(response *types.HTTPResponse) []types.CheckMetric {
+	if r == nil {
+		return nil
+	}
+	condition := r.checks.condition
+	var results []types.CheckMetric
+	if condition.StatusCode != 0 {
+		results = append(results, types.CheckMetric{
+			Id:     fmt.Sprintf("status_code == %d", condition.StatusCode),
+			Passed: response.Error == nil && response.StatusCode == condition.StatusCode,
+		})
+	}
+	for _, expected := range condition.Headers {
+		passed := false
+		for _, header := range response.Headers {
+			if http.CanonicalHeaderKey(header.Name) == http.CanonicalHeaderKey(expected.Name) && header.Value == expected.Value {
+				passed = true
+				break
+			}
+		}
+		results = append(results, types.CheckMetric{
+			Id:     fmt.Sprintf("header %s == %s", expected.Name, expected.Value),
+			Passed: passed,
+		})
+	}
+	if r.body != nil {
+		results = append(results, types.CheckMetric{
+			Id:     fmt.Sprintf("body contains %q", condition.Body),
+			Passed: response.Error == nil && r.body.Passed(),
+		})
+	}
+	if r.checks.bodyRegex != nil {
+		results = append(results, types.CheckMetric{
+			Id:     fmt.Sprintf("body matches /%s/", condition.BodyRegex),
+			Passed: response.Error == nil && r.checks.bodyRegex.MatchString(response.Body),
+		})
+	}
+	return results
+}
@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"goload/types"
+	"testing"
+)
+
+func TestBodyRegexNeedsTheFullBody(t *testing.T) {
+	checks, err := NewChecks(&CheckCondition{BodyRegex: `order-\d+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []types.BodyMode{"", types.FullBody} {
+		if err := checks.checkBodyMode(mode); err != nil {
+			t.Errorf("mode %q: %s", mode, err)
+		}
+	}
+	for _, mode := range []types.BodyMode{types.PrefixBody, types.DiscardBody} {
+		if err := checks.checkBodyMode(mode); err == nil {
+			t.Errorf("mode %q: expected an error", mode)
+		}
+	}
+
+	global := &Global{ResponseBody: &types.ResponseBodyConfig{Mode: types.AutoBody}}
+	if mode := global.responseBodyMode(); mode != "" {
+		t.Errorf("auto mode reported as %q", mode)
+	}
+	if options := global.clientOptions(checks.NeedsFullBody()); options.ResponseBody.Mode != types.FullBody {
+		t.Errorf("got mode %q, want full", options.ResponseBody.Mode)
+	}
+
+	var none *Checks
+	if err := none.checkBodyMode(types.DiscardBody); err != nil {
+		t.Errorf("without checks: %s", err)
+	}
+}
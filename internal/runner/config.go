@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"goload/internal/client"
 	"goload/types"
 	"strconv"
 	"strings"
@@ -8,29 +9,235 @@ import (
 )
 
 type Collection struct {
-	Name  string `yaml:"name"`
-	Tests []Test `yaml:"tests"`
+	Name        string                   `yaml:"name"`
+	Global      *Global                  `yaml:"global,omitempty"` // Defaults of the global settings of every test
+	Summary     *SummaryConfig           `yaml:"summary,omitempty"`
+	Timeseries  *TimeseriesConfig        `yaml:"timeseries,omitempty"`
+	Results     *ResultsConfig           `yaml:"results,omitempty"`
+	Prometheus  *PrometheusConfig        `yaml:"prometheus,omitempty"`
+	RemoteWrite *types.RemoteWriteConfig `yaml:"remote_write,omitempty"`
+	Influx      *types.InfluxConfig      `yaml:"influx,omitempty"`
+	StatsD      *types.StatsDConfig      `yaml:"statsd,omitempty"`
+	OTLP        *types.OTLPConfig        `yaml:"otlp,omitempty"`
+	Tests       []Test                   `yaml:"tests"`
+}
+
+// ResultsConfig streams the result of every request to a file.
+type ResultsConfig struct {
+	File       string `yaml:"file"`                  // JSON lines, or CSV for .csv files; gzipped for .gz files
+	Gzip       bool   `yaml:"gzip,omitempty"`        // Gzip whatever the extension
+	BufferSize int    `yaml:"buffer_size,omitempty"` // Results queued before dropping, default 10000
+}
+
+// PrometheusConfig serves the live metrics during the run.
+type PrometheusConfig struct {
+	Address string    `yaml:"address"`           // e.g. ":9090"
+	Path    string    `yaml:"path,omitempty"`    // Default /metrics
+	Labels  []string  `yaml:"labels,omitempty"`  // Tags turned into labels, every tag by default
+	Buckets []float64 `yaml:"buckets,omitempty"` // Latency histogram upper bounds in seconds
+}
+
+// TimeseriesConfig enables the per-interval request stats: RPS, active VUs,
+// error rate and latency percentiles.
+type TimeseriesConfig struct {
+	Interval     time.Duration `yaml:"interval,omitempty"`      // Default 1s
+	MaxIntervals int           `yaml:"max_intervals,omitempty"` // Intervals kept in memory, default 3600
+	File         string        `yaml:"file,omitempty"`          // Every interval is appended to it, as CSV for .csv files and JSON lines otherwise
+}
+
+// SummaryConfig controls the stats printed at the end of the run.
+type SummaryConfig struct {
+	GroupBy [][]string `yaml:"group_by,omitempty"` // Tag combinations the request stats are broken down by, e.g. [[test, phase]]
+	JSON    string     `yaml:"json,omitempty"`     // Path of the JSON summary
+	CSV     string     `yaml:"csv,omitempty"`      // Path of the CSV summary
+	HTML    string     `yaml:"html,omitempty"`     // Path of the HTML report
+	JUnit   string     `yaml:"junit,omitempty"`    // Path of the JUnit XML report
 }
 
 type Test struct {
-	Name    string            `yaml:"name"`
-	Global  *Global           `yaml:"global,omitempty"`
-	Request types.HTTPRequest `yaml:"request"`
-	Phases  []Phase           `yaml:"phases"`
+	Name       string               `yaml:"name"`
+	Global     *Global              `yaml:"global,omitempty"`
+	Request    types.HTTPRequest    `yaml:"request"`
+	Auth       *types.AuthConfig    `yaml:"auth,omitempty"` // Default auth of the test requests
+	Signing    *types.SigningConfig `yaml:"signing,omitempty"`
+	Response   *CheckCondition      `yaml:"response,omitempty"`
+	Tags       map[string]string    `yaml:"tags,omitempty"` // Added to the tags of the request metrics
+	Thresholds *types.Thresholds    `yaml:"thresholds,omitempty"`
+	Phases     []Phase              `yaml:"phases"`
+}
+
+// validateStreams checks the sse streams and the websocket scenarios of the
+// test and phase requests.
+func (t *Test) validateStreams() error {
+	for _, request := range t.requests() {
+		if request.SSE != nil {
+			if err := request.SSE.Validate(); err != nil {
+				return err
+			}
+		}
+		if request.WebSocket != nil {
+			if err := request.WebSocket.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRateLimits checks the rate limits of the test and phase requests.
+func (t *Test) validateRateLimits() error {
+	for _, request := range t.requests() {
+		if request.RateLimit != nil {
+			if err := request.RateLimit.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
+// requests returns the request of the test and the ones of its phases.
+func (t *Test) requests() []*types.HTTPRequest {
+	requests := []*types.HTTPRequest{&t.Request}
+	for _, phase := range t.Phases {
+		if phase.Request != nil {
+			requests = append(requests, phase.Request)
+		}
+	}
+	return requests
+}
+
+// CheckCondition lists what the responses of a test are expected to look
+// like. Every expectation set becomes a check reported in the stats.
 type CheckCondition struct {
-	Name    int                      `yaml:"status_code"`
-	Headers []types.HTTPClientHeader `yaml:"headers"`
-	Body    string                   `yaml:"body"`
+	StatusCode int                      `yaml:"status_code"`
+	Headers    []types.HTTPClientHeader `yaml:"headers"`
+	Body       string                   `yaml:"body"`       // Substring, matched while the body streams
+	BodyRegex  string                   `yaml:"body_regex"` // Needs the full body to be kept
 }
 
 type Global struct {
-	Timeout      time.Duration  `yaml:"timeout,omitempty"` // e.g., "30s"
-	Retries      int            `yaml:"retries,omitempty"` // Number of retries per request
-	RetriesDelay time.Duration  `yaml:"retries_delay,omitempty"`
-	ThinkTime    *time.Duration `yaml:"think_time,omitempty"` // Delay between requests per VU
+	Timeout      time.Duration             `yaml:"timeout,omitempty"` // e.g., "30s"
+	Retries      int                       `yaml:"retries,omitempty"` // Number of retries per request
+	RetriesDelay time.Duration             `yaml:"retries_delay,omitempty"`
+	ThinkTime    *time.Duration            `yaml:"think_time,omitempty"` // Delay between requests per VU
+	Redirect     *types.RedirectPolicy     `yaml:"redirect,omitempty"`
+	CookieJar    *CookieJar                `yaml:"cookie_jar,omitempty"`
+	ResponseBody *types.ResponseBodyConfig `yaml:"response_body,omitempty"`
+	Proxy        *types.ProxyConfig        `yaml:"proxy,omitempty"`
+	DNS          *types.DNSConfig          `yaml:"dns,omitempty"`
+}
+
+// withDefaults returns g with the settings it leaves unset taken from
+// defaults, the collection global settings.
+func (g *Global) withDefaults(defaults *Global) *Global {
+	if g == nil {
+		return defaults
+	}
+	if defaults == nil {
+		return g
+	}
+	merged := *g
+	if merged.Timeout == 0 {
+		merged.Timeout = defaults.Timeout
+	}
+	if merged.Retries == 0 {
+		merged.Retries = defaults.Retries
+	}
+	if merged.RetriesDelay == 0 {
+		merged.RetriesDelay = defaults.RetriesDelay
+	}
+	if merged.ThinkTime == nil {
+		merged.ThinkTime = defaults.ThinkTime
+	}
+	if merged.Redirect == nil {
+		merged.Redirect = defaults.Redirect
+	}
+	if merged.CookieJar == nil {
+		merged.CookieJar = defaults.CookieJar
+	}
+	if merged.ResponseBody == nil {
+		merged.ResponseBody = defaults.ResponseBody
+	}
+	if merged.Proxy == nil {
+		merged.Proxy = defaults.Proxy
+	}
+	if merged.DNS == nil {
+		merged.DNS = defaults.DNS
+	}
+	return &merged
+}
+
+func (g *Global) dns() *types.DNSConfig {
+	if g == nil {
+		return nil
+	}
+	return g.DNS
+}
+
+func (g *Global) proxy() *types.ProxyConfig {
+	if g == nil {
+		return nil
+	}
+	return g.Proxy
+}
+
+// CookieJar configures the cookie jar owned by each VU. The jar is enabled
+// unless disabled explicitly.
+type CookieJar struct {
+	Disabled           bool `yaml:"disabled,omitempty"`
+	SeedFromRequest    bool `yaml:"seed_from_request,omitempty"` // Store the cookies of each request in the jar the first time it is sent
+	ClearEachIteration bool `yaml:"clear_each_iteration,omitempty"`
 }
+
+func (j *CookieJar) seeds() bool {
+	return j != nil && !j.Disabled && j.SeedFromRequest
+}
+
+func (g *Global) cookieJar() *CookieJar {
+	if g == nil {
+		return nil
+	}
+	return g.CookieJar
+}
+
+func (g *Global) responseBody() *types.ResponseBodyConfig {
+	if g == nil {
+		return nil
+	}
+	return g.ResponseBody
+}
+
+// responseBodyMode is the response_body mode set explicitly, empty when it
+// is left to the checks.
+func (g *Global) responseBodyMode() types.BodyMode {
+	if g.responseBody() == nil || g.ResponseBody.Mode == types.AutoBody {
+		return ""
+	}
+	return g.ResponseBody.Mode
+}
+
+func (g *Global) clientOptions(needsFullBody bool) client.Options {
+	options := client.Options{CookieJar: true}
+	if g != nil {
+		options.Timeout = g.Timeout
+		options.Redirect = g.Redirect
+		options.CookieJar = g.CookieJar == nil || !g.CookieJar.Disabled
+		options.Proxy = g.Proxy
+		options.DNS = g.DNS
+		if g.ResponseBody != nil {
+			options.ResponseBody = *g.ResponseBody
+		}
+	}
+	if options.ResponseBody.Mode == "" || options.ResponseBody.Mode == types.AutoBody {
+		options.ResponseBody.Mode = types.DiscardBody
+		if needsFullBody {
+			options.ResponseBody.Mode = types.FullBody
+		}
+	}
+	return options
+}
+
 type Phase struct {
 	Name          string             `yaml:"name"`
 	SingleRequest bool               `yaml:"single_request,omitempty"`
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"goload/types"
+	"testing"
+	"time"
+)
+
+func TestValidateStreamsChecksPhaseRequests(t *testing.T) {
+	test := &Test{
+		Request: types.HTTPRequest{Kind: types.SSEKind, SSE: &types.SSEStream{Expect: `"ok"`}},
+		Phases: []Phase{
+			{Name: "warmup"},
+			{Name: "peak", Request: &types.HTTPRequest{Kind: types.SSEKind, SSE: &types.SSEStream{Expect: "(["}}},
+		},
+	}
+	if err := test.validateStreams(); err == nil {
+		t.Error("expected the phase expect pattern to be rejected")
+	}
+	test.Phases[1].Request.SSE.Expect = `\d+`
+	if err := test.validateStreams(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResponseBodyModeIsValidated(t *testing.T) {
+	for mode, valid := range map[types.BodyMode]bool{
+		"":                true,
+		types.AutoBody:    true,
+		types.DiscardBody: true,
+		types.PrefixBody:  true,
+		types.FullBody:    true,
+		"ful":             false,
+		"Full":            false,
+	} {
+		global := &Global{ResponseBody: &types.ResponseBodyConfig{Mode: mode}}
+		if err := global.responseBody().Validate(); (err == nil) != valid {
+			t.Errorf("mode %q: got %v", mode, err)
+		}
+	}
+	if (*Global)(nil).responseBody() != nil {
+		t.Error("expected no response_body without a global block")
+	}
+}
+
+func TestValidateStreamsChecksWebSocketSteps(t *testing.T) {
+	test := &Test{
+		Request: types.HTTPRequest{Kind: types.WebSocketKind, WebSocket: &types.WebSocketScenario{Steps: []types.WebSocketStep{
+			{Send: "ping", Expect: "^pong$"},
+			{Expect: "(["},
+		}}},
+	}
+	if err := test.validateStreams(); err == nil {
+		t.Error("expected the expect pattern of the second step to be rejected")
+	}
+	test.Request.WebSocket.Steps[1] = types.WebSocketStep{Wait: -time.Second}
+	if err := test.validateStreams(); err == nil {
+		t.Error("expected a negative wait to be rejected")
+	}
+	test.Request.WebSocket.Steps[1] = types.WebSocketStep{Expect: `\d+`}
+	if err := test.validateStreams(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	for _, test := range []struct {
+		limit types.RateLimit
+		valid bool
+	}{
+		{types.RateLimit{Rate: 10}, true},
+		{types.RateLimit{Rate: 0.5, Burst: 5, By: "host"}, true},
+		{types.RateLimit{Rate: 10, By: "name"}, true},
+		{types.RateLimit{}, false},
+		{types.RateLimit{Rate: -1}, false},
+		{types.RateLimit{Rate: 10, Burst: -1}, false},
+		{types.RateLimit{Rate: 10, By: "url"}, false},
+	} {
+		limit := test.limit
+		config := &Test{Phases: []Phase{{Name: "peak", Request: &types.HTTPRequest{RateLimit: &limit}}}}
+		if err := config.validateRateLimits(); (err == nil) != test.valid {
+			t.Errorf("%+v: got %v", test.limit, err)
+		}
+	}
+}
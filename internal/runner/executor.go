@@ -2,18 +2,29 @@ package runner
 
 import (
 	fmt "fmt"
+	"goload/internal/auth"
+	"goload/internal/client"
 	"goload/internal/logging"
 	"goload/internal/metrics"
+	"goload/internal/output"
+	"goload/internal/ratelimit"
+	"goload/internal/report"
 	"goload/types"
 	"gopkg.in/yaml.v3"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type Executor struct {
 	Collection      Collection
 	logger          logging.Logger
 	metricCollector metrics.MetricsCollector
+	auth            *auth.Registry
+	rateLimits      *ratelimit.Registry
+	traceSampleRate float64
+	config          []byte // YAML the collection was loaded from
 }
 
 func LoadFromYaml(yamlFilePath string) (*Executor, error) {
@@ -33,6 +44,7 @@ func LoadFromYaml(yamlFilePath string) (*Executor, error) {
 
 	executor := Executor{
 		Collection: Collection,
+		config:     yamlFile,
 	}
 	err = executor.load()
 	if err != nil {
@@ -67,6 +79,15 @@ func (e *Executor) Execute() {
 	if err != nil {
 		_ = fmt.Errorf("error initializing metrics collector: %s", err)
 	}
+	if err := e.enableTimeseries(); err != nil {
+		fmt.Printf("Error configuring the time series: %s\n", err)
+	}
+	if err := e.addOutputs(); err != nil {
+		fmt.Printf("Error configuring the outputs: %s\n", err)
+	}
+	e.auth = auth.NewRegistry(&e.metricCollector)
+	e.rateLimits = ratelimit.NewRegistry()
+	run := metrics.RunInfo{Collection: e.Collection.Name, Config: e.configSnapshot(), StartedAt: time.Now()}
 	_ = e.logger.Log(fmt.Sprintf("Executing %d tests", len(e.Collection.Tests)))
 	for _, test := range e.Collection.Tests {
 		if test.Name != "" {
@@ -74,38 +95,226 @@ func (e *Executor) Execute() {
 		} else {
 			fmt.Println("parsing test configuration")
 		}
+		test.Global = test.Global.withDefaults(e.Collection.Global)
+		if proxy := test.Global.proxy(); proxy != nil {
+			if _, err := proxy.URLs(); err != nil {
+				fmt.Printf("Error configuring the proxy: %s\n", err)
+				continue
+			}
+		}
+		if dns := test.Global.dns(); dns != nil {
+			if err := dns.Validate(); err != nil {
+				fmt.Printf("Error configuring dns: %s\n", err)
+				continue
+			}
+		}
+		if err := test.validateStreams(); err != nil {
+			fmt.Printf("Error configuring streams: %s\n", err)
+			continue
+		}
+		if err := test.validateRateLimits(); err != nil {
+			fmt.Printf("Error configuring rate_limit: %s\n", err)
+			continue
+		}
+		if test.Request.Auth == nil {
+			test.Request.Auth = test.Auth
+		}
+		var signers []client.Signer
+		if test.Signing != nil {
+			signer, err := client.NewSigner(test.Signing)
+			if err != nil {
+				fmt.Printf("Error configuring request signing: %s\n", err)
+				continue
+			}
+			signers = append(signers, signer)
+		}
+		checks, err := NewChecks(test.Response)
+		if err != nil {
+			fmt.Printf("Error configuring response checks: %s\n", err)
+			continue
+		}
+		if body := test.Global.responseBody(); body != nil {
+			if err := body.Validate(); err != nil {
+				fmt.Printf("Error configuring response_body: %s\n", err)
+				continue
+			}
+		}
+		if err := checks.checkBodyMode(test.Global.responseBodyMode()); err != nil {
+			fmt.Printf("Error configuring response checks: %s\n", err)
+			continue
+		}
+		vus := NewVUPool(test.Global, checks.NeedsFullBody())
+		testRun := metrics.TestRun{Name: test.Name, StartedAt: time.Now(), Thresholds: test.Thresholds}
 		for i, phase := range test.Phases {
 			if phase.Request == nil {
 				phase.Request = &test.Request
+			} else if phase.Request.Auth == nil {
+				phaseRequest := *phase.Request
+				phaseRequest.Auth = test.Auth
+				phase.Request = &phaseRequest
 			}
 			_ = e.logger.LogSeparator()
 			_ = e.logger.Log(fmt.Sprintf("Executing phase number : %d", i+1))
 			_ = e.logger.Log(phase.String())
 			_ = e.logger.Log(phase.Request.Summary())
 			_ = e.logger.LogSeparator()
-			err := e.executePhase(phase, test.Request, test.Global)
+			tags := map[string]string{"test": test.Name, "phase": phase.Name}
+			if phase.Name == "" {
+				tags["phase"] = fmt.Sprintf("phase-%d", i+1)
+			}
+			for name, value := range test.Tags {
+				tags[name] = value
+			}
+			err := e.executePhase(phase, test.Request, test.Global, vus, signers, checks, tags)
 			if err != nil {
 				_ = fmt.Errorf("failed to execute phase: %s", err)
 			}
 		}
+		vus.Close()
+		e.metricCollector.SetActiveVUs(0)
+		testRun.EndedAt = time.Now()
+		run.Tests = append(run.Tests, testRun)
 	}
+	run.EndedAt = time.Now()
 	e.metricCollector.StopWorkers()
+	if err := e.metricCollector.CloseTimeseries(); err != nil {
+		fmt.Printf("Error writing the time series: %s\n", err)
+	}
+	if err := e.metricCollector.CloseOutputs(); err != nil {
+		fmt.Printf("Error closing the outputs: %s\n", err)
+	}
+	if e.Collection.Summary != nil {
+		e.metricCollector.GroupBy = e.Collection.Summary.GroupBy
+	}
 	e.metricCollector.LogRequestsStats()
+
+	summary := e.metricCollector.Summary(run)
+	if table := summary.ThresholdsTable(); table != "" {
+		_ = e.logger.LogWithoutDate(table)
+	}
+	if e.Collection.Summary != nil {
+		e.Collection.Summary.writeFiles(summary)
+	}
 }
 
-func (e *Executor) executePhase(phase Phase, request types.HTTPRequest, global *Global) error {
+// writeFiles writes summary to the files of the config, each in the format
+// of its setting whatever the extension of its path.
+func (c *SummaryConfig) writeFiles(summary metrics.Summary) {
+	writers := []struct {
+		path  string
+		write func(string) error
+	}{
+		{c.JSON, summary.WriteJSONFile},
+		{c.CSV, summary.WriteCSVFile},
+		{c.HTML, func(path string) error { return report.WriteFile(path, summary) }},
+		{c.JUnit, summary.WriteJUnitFile},
+	}
+	for _, writer := range writers {
+		if writer.path == "" {
+			continue
+		}
+		if err := writer.write(writer.path); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+func (e *Executor) enableTimeseries() error {
+	config := e.Collection.Timeseries
+	if config == nil && e.Collection.Summary != nil && e.Collection.Summary.HTML != "" {
+		// The charts of the report are drawn from the time series
+		config = &TimeseriesConfig{}
+	}
+	if config == nil {
+		return nil
+	}
+	interval := config.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	if config.File == "" {
+		return e.metricCollector.EnableTimeseries(interval, config.MaxIntervals, "", nil)
+	}
+	output, err := os.Create(config.File)
+	if err != nil {
+		return err
+	}
+	return e.metricCollector.EnableTimeseries(interval, config.MaxIntervals, config.File, output)
+}
+
+// addOutputs registers the outputs the results of the requests are
+// streamed to.
+func (e *Executor) addOutputs() error {
+	if results := e.Collection.Results; results != nil && results.File != "" {
+		writer, err := output.NewResultsWriter(results.File, results.BufferSize, results.Gzip)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(writer)
+	}
+	if prometheus := e.Collection.Prometheus; prometheus != nil {
+		endpoint, err := output.NewPrometheus(prometheus.Address, prometheus.Path, prometheus.Labels, prometheus.Buckets, &e.metricCollector)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(endpoint)
+	}
+	if e.Collection.RemoteWrite != nil {
+		remoteWrite, err := output.NewRemoteWrite(*e.Collection.RemoteWrite, &e.metricCollector)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(remoteWrite)
+	}
+	if e.Collection.Influx != nil {
+		influx, err := output.NewInflux(*e.Collection.Influx, &e.metricCollector)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(influx)
+	}
+	if e.Collection.StatsD != nil {
+		statsd, err := output.NewStatsD(*e.Collection.StatsD, &e.metricCollector)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(statsd)
+	}
+	if e.Collection.OTLP != nil {
+		otlp, err := output.NewOTLP(*e.Collection.OTLP, &e.metricCollector)
+		if err != nil {
+			return err
+		}
+		e.metricCollector.AddOutput(otlp)
+		e.traceSampleRate = e.Collection.OTLP.TraceSampleRate()
+	}
+	return nil
+}
+
+func (e *Executor) executePhase(phase Phase, request types.HTTPRequest, global *Global, vus *VUPool, signers []client.Signer, checks *Checks, tags map[string]string) error {
 	executionSegment, err := ResolvePhase(phase)
 	if err != nil {
 		fmt.Printf("Error resolving phase: %s\n", err)
 		return nil
 	}
-	for {
+	for segment := 1; ; segment++ {
 		if executionSegment == nil {
 			break
 		}
+		segmentTags := map[string]string{"segment": strconv.Itoa(segment)}
+		for name, value := range tags {
+			segmentTags[name] = value
+		}
 		runner := SegmentRunner{
 			MetricsCollector: &e.metricCollector,
 			Logger:           &e.logger,
+			VUs:              vus,
+			Auth:             e.auth,
+			RateLimits:       e.rateLimits,
+			Signers:          signers,
+			Checks:           checks,
+			Tags:             segmentTags,
+			TraceSampleRate:  e.traceSampleRate,
 		}
 		err = runner.Run(executionSegment, request, global)
 		if err != nil {
@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"goload/internal/metrics"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummaryFilesIgnoreTheirExtension(t *testing.T) {
+	dir := t.TempDir()
+	config := &SummaryConfig{
+		JSON: filepath.Join(dir, "summary"),
+		CSV:  filepath.Join(dir, "summary.out"),
+	}
+	config.writeFiles(metrics.Summary{SchemaVersion: metrics.SummarySchemaVersion, Collection: "shop"})
+
+	if _, err := metrics.ReadSummary(config.JSON); err != nil {
+		t.Errorf("json summary: %s", err)
+	}
+	csv, err := os.ReadFile(config.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(csv), "group_by,tags,requests,") {
+		t.Errorf("csv summary: got %q", csv)
+	}
+}
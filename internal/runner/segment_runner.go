@@ -1,10 +1,13 @@
 package runner
 
 import (
+	"context"
 	"fmt"
+	"goload/internal/auth"
 	"goload/internal/client"
 	"goload/internal/logging"
 	"goload/internal/metrics"
+	"goload/internal/ratelimit"
 	"goload/types"
 	"net/http"
 	"sync"
@@ -27,7 +30,13 @@ type SegmentExecutionMetrics struct {
 type SegmentRunner struct {
 	MetricsCollector *metrics.MetricsCollector
 	Logger           *logging.Logger
-	Client           client.Client
+	VUs              *VUPool
+	Auth             *auth.Registry
+	RateLimits       *ratelimit.Registry
+	Signers          []client.Signer
+	Checks           *Checks
+	Tags             map[string]string // Test, phase and segment tags of the request metrics
+	TraceSampleRate  float64           // Share of the requests traced, none when 0
 }
 
 func (runner *SegmentRunner) Run(segment *Segment, httpRequest types.HTTPRequest, global *Global) error {
@@ -35,12 +44,10 @@ func (runner *SegmentRunner) Run(segment *Segment, httpRequest types.HTTPRequest
 		httpRequest = *segment.Request
 	}
 
+	runner.MetricsCollector.SetActiveVUs(segment.TargetVUs)
 	var wg sync.WaitGroup
 	startTIme := time.Now()
 	first := true
-	httpClient := &client.Client{
-		HttpClient: &http.Client{},
-	}
 	for {
 		if (segment.Duration == nil && !first) || (segment.Duration != nil && time.Since(startTIme) >= *segment.Duration) {
 			break
@@ -48,24 +55,171 @@ func (runner *SegmentRunner) Run(segment *Segment, httpRequest types.HTTPRequest
 		first = false
 		for i := 0; i < segment.TargetVUs; i++ {
 			wg.Add(1)
+			vu := runner.VUs.Get(i)
 			go func() {
-				request, err := client.CreateRequest(httpRequest)
-				if err != nil {
-					_ = fmt.Errorf("error creating the httpRequest: %s\n", err)
-				}
-				response, err := httpClient.ExecuteRequest(request)
-				runner.Logger.LogResponse(*response)
-				err = runner.MetricsCollector.IngestRequestMetric(*response.RequestMetric)
-				if err != nil {
-					fmt.Printf("error ingesting request metric: %s\n", err)
-				}
+				defer wg.Done()
+				vu.beginIteration()
+				runner.iterate(vu, httpRequest)
 				if global != nil && global.ThinkTime != nil {
 					time.Sleep(*global.ThinkTime)
 				}
-				wg.Done()
 			}()
 		}
 		wg.Wait()
 	}
 	return nil
 }
+
+// iterate sends one request on behalf of vu and records its outcome.
+func (runner *SegmentRunner) iterate(vu *VirtualUser, httpRequest types.HTTPRequest) {
+	httpRequest = runner.startSpan(vu, httpRequest)
+	if runner.RateLimits != nil {
+		// The wait is recorded apart and happens before the request timing starts
+		rateLimitMetric, err := runner.RateLimits.Wait(context.Background(), httpRequest)
+		if err != nil {
+			err = fmt.Errorf("error applying the rate limit: %s", err)
+			_ = runner.Logger.Log(err.Error())
+			runner.ingestResponse(vu, httpRequest, failedResponse("", err))
+			return
+		}
+		if rateLimitMetric != nil {
+			_ = runner.MetricsCollector.IngestRateLimitMetric(*rateLimitMetric)
+		}
+	}
+	runner.MetricsCollector.RequestStarted()
+	response := runner.executeHTTP(vu, httpRequest)
+	runner.MetricsCollector.RequestFinished()
+	if response == nil {
+		return
+	}
+	_ = runner.Logger.LogResponse(*response)
+	runner.ingestResponse(vu, httpRequest, response)
+}
+
+func (runner *SegmentRunner) executeHTTP(vu *VirtualUser, httpRequest types.HTTPRequest) *types.HTTPResponse {
+	if httpRequest.Kind == types.GRPCKind {
+		return runner.executeGRPC(vu, httpRequest)
+	}
+	if httpRequest.Kind == types.TCPKind || httpRequest.Kind == types.UDPKind {
+		return runner.executeSocket(vu, httpRequest)
+	}
+
+	signers := runner.Signers
+	if httpRequest.Auth != nil {
+		signers = append([]client.Signer{runner.Auth.Signer(httpRequest.Auth)}, signers...)
+	}
+	request, err := client.CreateRequest(vu.prepare(httpRequest), signers...)
+	if err != nil {
+		err = fmt.Errorf("error creating the httpRequest: %s", err)
+		_ = runner.Logger.Log(err.Error())
+		runner.ingestResponse(vu, httpRequest, failedResponse("", err))
+		return nil
+	}
+
+	if httpRequest.Kind == types.WebSocketKind {
+		if httpRequest.WebSocket == nil {
+			err := fmt.Errorf("websocket request requires a websocket scenario")
+			_ = runner.Logger.Log(err.Error())
+			runner.ingestResponse(vu, httpRequest, failedResponse("websocket", err))
+			return nil
+		}
+		response, _ := vu.Client.ExecuteWebSocket(request, httpRequest.WebSocket)
+		_ = runner.MetricsCollector.IngestWebSocketMetric(*response.WebSocket)
+		return response
+	}
+
+	if httpRequest.Kind == types.SSEKind {
+		stream := httpRequest.SSE
+		if stream == nil {
+			// Without limits the stream is read until the server ends it
+			stream = &types.SSEStream{}
+		}
+		response, err := vu.Client.ExecuteSSE(request, stream)
+		if err != nil && response.RequestMetric.NotSent {
+			_ = runner.Logger.Log(err.Error())
+			return response
+		}
+		_ = runner.MetricsCollector.IngestSSEMetric(*response.SSE)
+		return response
+	}
+
+	checks := runner.Checks.forRequest()
+	bodyChecks := checks.bodyChecks()
+	var graphQLErrors *client.GraphQLErrorsCheck
+	if httpRequest.Kind == types.GraphQLKind {
+		graphQLErrors = client.NewGraphQLErrorsCheck()
+		bodyChecks = append(bodyChecks, graphQLErrors)
+	}
+
+	response, _ := vu.Client.ExecuteRequest(request, bodyChecks...)
+	if graphQLErrors != nil {
+		runner.judgeGraphQL(httpRequest.GraphQL, graphQLErrors, response)
+	}
+	for _, check := range checks.evaluate(response) {
+		check.Test = runner.Tags["test"]
+		_ = runner.MetricsCollector.IngestCheckMetric(check)
+	}
+	return response
+}
+
+// judgeGraphQL fails responses carrying GraphQL errors, even with a 200
+// status code, and reports the errors of the operation.
+func (runner *SegmentRunner) judgeGraphQL(operation *types.GraphQLRequest, graphQLErrors *client.GraphQLErrorsCheck, response *types.HTTPResponse) {
+	errors, valid := graphQLErrors.Errors()
+	if response.Error != nil {
+		return
+	}
+	failed := errors > 0 || !valid
+	if failed {
+		response.RequestMetric.Failed = true
+	}
+	_ = runner.MetricsCollector.IngestGraphQLMetric(types.GraphQLMetric{
+		OperationName: operation.OperationName,
+		Errors:        errors,
+		Failed:        failed,
+	})
+}
+
+// executeGRPC sends the request headers, and the auth header, as metadata.
+func (runner *SegmentRunner) executeGRPC(vu *VirtualUser, httpRequest types.HTTPRequest) *types.HTTPResponse {
+	if httpRequest.GRPC == nil {
+		err := fmt.Errorf("grpc request requires a grpc call")
+		_ = runner.Logger.Log(err.Error())
+		runner.ingestResponse(vu, httpRequest, failedResponse("grpc", err))
+		return nil
+	}
+	header := http.Header{}
+	for _, requestHeader := range httpRequest.Headers {
+		header.Add(requestHeader.Name, requestHeader.Value)
+	}
+	if httpRequest.Auth != nil {
+		authRequest := &http.Request{Header: header}
+		if err := runner.Auth.Apply(httpRequest.Auth, authRequest); err != nil {
+			err = fmt.Errorf("error authenticating the grpc call: %s", err)
+			_ = runner.Logger.Log(err.Error())
+			runner.ingestResponse(vu, httpRequest, failedResponse("grpc", err))
+			return nil
+		}
+	}
+
+	response, _ := vu.Client.ExecuteGRPC(context.Background(), httpRequest.GRPC, header)
+	_ = runner.MetricsCollector.IngestGRPCMetric(*response.GRPC)
+	return response
+}
+
+func (runner *SegmentRunner) executeSocket(vu *VirtualUser, httpRequest types.HTTPRequest) *types.HTTPResponse {
+	var response *types.HTTPResponse
+	switch {
+	case httpRequest.Kind == types.TCPKind && httpRequest.TCP != nil:
+		response, _ = vu.Client.ExecuteTCP(context.Background(), httpRequest.TCP)
+	case httpRequest.Kind == types.UDPKind && httpRequest.UDP != nil:
+		response, _ = vu.Client.ExecuteUDP(context.Background(), httpRequest.UDP)
+	default:
+		err := fmt.Errorf("%s request requires a %s section", httpRequest.Kind, httpRequest.Kind)
+		_ = runner.Logger.Log(err.Error())
+		runner.ingestResponse(vu, httpRequest, failedResponse(string(httpRequest.Kind), err))
+		return nil
+	}
+	_ = runner.MetricsCollector.IngestSocketMetric(*response.Socket)
+	return response
+}
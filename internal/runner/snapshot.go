@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strings"
+)
+
+// sensitiveKeys are redacted from the configuration snapshot, as keys or as
+// the name of a header. Hyphens match underscores, e.g. X-API-Key.
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "api_key", "apikey", "cookie"}
+
+// urlUserinfo matches the credentials of a URL, e.g. a proxy URL.
+var urlUserinfo = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/?#@\s]+@`)
+
+// urlParam matches a name=value pair of a query string or of a form body.
+var urlParam = regexp.MustCompile(`(^|[?&])([^=&?#\s]+)=([^&#\s]*)`)
+
+// jsonField matches a "name": "value" pair of a JSON body.
+var jsonField = regexp.MustCompile(`("([^"\\]+)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// configSnapshot returns the configuration of the run with its secrets
+// redacted, for the summary.
+func (e *Executor) configSnapshot() string {
+	var root yaml.Node
+	if len(e.config) > 0 {
+		if err := yaml.Unmarshal(e.config, &root); err != nil {
+			return ""
+		}
+	} else if err := root.Encode(e.Collection); err != nil {
+		return ""
+	}
+	redact(&root, false)
+	snapshot, err := yaml.Marshal(&root)
+	if err != nil {
+		return ""
+	}
+	return string(snapshot)
+}
+
+// redact replaces the sensitive values under node, and the credentials of
+// URLs. Under a sensitive key, like cookies, the value of each name/value
+// pair is sensitive too.
+func redact(node *yaml.Node, sensitive bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		node.Value = redactString(node.Value)
+	case yaml.MappingNode:
+		sensitiveValue := sensitive
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "name" && isSensitive(node.Content[i+1].Value) {
+				sensitiveValue = true
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.ScalarNode && (isSensitive(key.Value) || (sensitiveValue && key.Value == "value")) {
+				value.Value = "REDACTED"
+				value.Tag = "!!str"
+				value.Style = 0
+				continue
+			}
+			redact(value, isSensitive(key.Value))
+		}
+		return
+	}
+	for _, child := range node.Content {
+		redact(child, sensitive)
+	}
+}
+
+// redactString replaces the credentials of the URLs in value, and the values
+// of its sensitive query parameters, form fields and JSON fields.
+func redactString(value string) string {
+	value = urlUserinfo.ReplaceAllString(value, "${1}REDACTED@")
+	value = urlParam.ReplaceAllStringFunc(value, func(param string) string {
+		match := urlParam.FindStringSubmatch(param)
+		if !isSensitive(match[2]) {
+			return param
+		}
+		return match[1] + match[2] + "=REDACTED"
+	})
+	return jsonField.ReplaceAllStringFunc(value, func(field string) string {
+		match := jsonField.FindStringSubmatch(field)
+		if !isSensitive(match[2]) {
+			return field
+		}
+		return match[1] + `"REDACTED"`
+	})
+}
+
+func isSensitive(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"fmt"
+	"goload/internal/metrics"
+	"goload/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ingestResponse records the request and network metrics of response and
+// publishes its result to the outputs. Only the request metric is tagged,
+// with the tags of the segment, the tags describing httpRequest and its
+// custom tags; the protocol metrics are recorded by their callers untagged.
+func (runner *SegmentRunner) ingestResponse(vu *VirtualUser, httpRequest types.HTTPRequest, response *types.HTTPResponse) {
+	metric := *response.RequestMetric
+	tags := make(map[string]string, len(runner.Tags)+len(httpRequest.Tags)+5)
+	for name, value := range runner.Tags {
+		tags[name] = value
+	}
+	if httpRequest.Name != "" {
+		tags["name"] = httpRequest.Name
+	}
+	tags["method"] = requestMethod(httpRequest)
+	tags["url"] = urlTemplate(httpRequest)
+	if metric.Protocol != "" {
+		tags["protocol"] = metric.Protocol
+	}
+	tags["status"] = strconv.Itoa(metric.StatusCode)
+	if metric.StatusCode == 0 && metric.Failed {
+		tags["status"] = "error"
+	}
+	for name, value := range httpRequest.Tags {
+		tags[name] = value
+	}
+	metric.Tags = tags
+	if metric.Timestamp.IsZero() {
+		metric.Timestamp = time.Now()
+	}
+	if response.Error != nil {
+		metric.Error = response.Error.Error()
+	}
+	if err := runner.MetricsCollector.IngestRequestMetric(metric); err != nil {
+		fmt.Printf("error ingesting request metric: %s\n", err)
+	}
+	if response.NetworkMetric != nil {
+		_ = runner.MetricsCollector.IngestNetworkMetric(*response.NetworkMetric)
+	}
+
+	result := metrics.Result{
+		Timestamp: metric.Timestamp,
+		VU:        vu.Id,
+		Iteration: vu.Iteration,
+		Test:      tags["test"],
+		Phase:     tags["phase"],
+		Name:      httpRequest.Name,
+		Method:    tags["method"],
+		URL:       tags["url"],
+		Protocol:  metric.Protocol,
+		Status:    metric.StatusCode,
+		Failed:    !metric.Succeeded(),
+		NoLatency: !metric.HasLatency(),
+		Duration:  metric.Duration,
+		Timing:    metric.Timing,
+		Error:     metric.Error,
+		Tags:      tags,
+		TraceID:   vu.traceID,
+		SpanID:    vu.spanID,
+	}
+	if response.NetworkMetric != nil {
+		result.BytesSent = response.NetworkMetric.BytesSent
+		result.BytesRecv = response.NetworkMetric.BytesRecv
+	}
+	runner.MetricsCollector.PublishResult(result)
+}
+
+// failedResponse describes a request that could not be sent.
+func failedResponse(protocol string, err error) *types.HTTPResponse {
+	return &types.HTTPResponse{
+		Error:         err,
+		RequestMetric: &types.RequestMetric{Protocol: protocol, Failed: true, NotSent: true},
+	}
+}
+
+func requestMethod(request types.HTTPRequest) string {
+	switch request.Kind {
+	case "", types.HTTPKind, types.GraphQLKind, types.SSEKind:
+		if request.Method != "" {
+			return strings.ToUpper(string(request.Method))
+		}
+		if request.Kind == types.GraphQLKind {
+			return http.MethodPost
+		}
+		return http.MethodGet
+	default:
+		return strings.ToUpper(string(request.Kind))
+	}
+}
+
+// urlTemplate identifies the endpoint of request: its URI without the query
+// string, which often carries per-request values.
+func urlTemplate(request types.HTTPRequest) string {
+	switch {
+	case request.Kind == types.GRPCKind && request.GRPC != nil:
+		return request.GRPC.Target + "/" + strings.TrimPrefix(request.GRPC.Method, "/")
+	case request.Kind == types.TCPKind && request.TCP != nil:
+		return "tcp://" + request.TCP.Address
+	case request.Kind == types.UDPKind && request.UDP != nil:
+		return "udp://" + request.UDP.Address
+	}
+	uri, _, _ := strings.Cut(request.URI, "?")
+	return uri
+}
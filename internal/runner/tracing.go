@@ -0,0 +1,37 @@
+package runner
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"goload/types"
+	"math/rand/v2"
+	"strings"
+)
+
+// startSpan decides whether the request of the iteration is traced. When it
+// is, the VU keeps the ids of its client span and the returned request
+// carries them in a W3C traceparent header.
+func (runner *SegmentRunner) startSpan(vu *VirtualUser, httpRequest types.HTTPRequest) types.HTTPRequest {
+	vu.traceID, vu.spanID = "", ""
+	if runner.TraceSampleRate <= 0 || rand.Float64() >= runner.TraceSampleRate {
+		return httpRequest
+	}
+	vu.traceID, vu.spanID = randomID(16), randomID(8)
+	headers := make([]types.HTTPClientHeader, 0, len(httpRequest.Headers)+1)
+	for _, header := range httpRequest.Headers {
+		if !strings.EqualFold(header.Name, "traceparent") {
+			headers = append(headers, header)
+		}
+	}
+	httpRequest.Headers = append(headers, types.HTTPClientHeader{
+		Name:  "traceparent",
+		Value: "00-" + vu.traceID + "-" + vu.spanID + "-01",
+	})
+	return httpRequest
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	_, _ = crand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"goload/internal/client"
+	"goload/types"
+	"net/url"
+	"sync"
+)
+
+// VirtualUser is the per-VU state kept for the whole test: its client, and
+// with it the cookie jar holding the VU session.
+type VirtualUser struct {
+	Id        int
+	Client    *client.Client
+	Iteration int
+	global    *Global
+	traceID   string // Client span of the current request, when it is traced
+	spanID    string
+	seeded    map[string]bool // Cookies of the requests already seeded in the jar
+}
+
+// beginIteration is called before each request the VU sends.
+func (vu *VirtualUser) beginIteration() {
+	vu.Iteration++
+	if proxy := vu.global.proxy(); proxy != nil && proxy.Rotate && vu.Iteration > 1 {
+		vu.Client.RotateProxy()
+	}
+	jar := vu.global.cookieJar()
+	if jar == nil || !jar.ClearEachIteration || vu.Iteration == 1 {
+		return
+	}
+	vu.Client.ResetCookies()
+	vu.seeded = nil
+}
+
+// prepare returns the request the VU should send. The cookies of a request
+// are seeded in the jar the first time the VU sends them to a host, then left
+// to the jar, so they are not sent a second time as explicit headers and the
+// values set by the server replace them.
+func (vu *VirtualUser) prepare(request types.HTTPRequest) types.HTTPRequest {
+	if !vu.global.cookieJar().seeds() || len(request.Cookies) == 0 {
+		return request
+	}
+	if vu.seeded == nil {
+		vu.seeded = make(map[string]bool)
+	}
+	host := request.URI
+	if parsedURL, err := url.Parse(request.URI); err == nil {
+		host = parsedURL.Host
+	}
+	var unseeded []types.HTTPClientCookie
+	for _, cookie := range request.Cookies {
+		key := host + "\x00" + cookie.Name + "\x00" + cookie.Value
+		if !vu.seeded[key] {
+			vu.seeded[key] = true
+			unseeded = append(unseeded, cookie)
+		}
+	}
+	_ = vu.Client.SeedCookies(request.URI, unseeded)
+	request.Cookies = nil
+	return request
+}
+
+// VUPool hands out VUs by index, creating them on first use, so that VUs keep
+// their session across iterations, segments and phases of a test.
+type VUPool struct {
+	global        *Global
+	needsFullBody bool
+	users         []*VirtualUser
+	mu            sync.Mutex
+}
+
+func NewVUPool(global *Global, needsFullBody bool) *VUPool {
+	return &VUPool{
+		global:        global,
+		needsFullBody: needsFullBody,
+	}
+}
+
+func (pool *VUPool) Get(index int) *VirtualUser {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for len(pool.users) <= index {
+		options := pool.global.clientOptions(pool.needsFullBody)
+		options.ProxyOffset = len(pool.users)
+		vu := &VirtualUser{
+			Id:     len(pool.users) + 1,
+			Client: client.NewClient(options),
+			global: pool.global,
+		}
+		pool.users = append(pool.users, vu)
+	}
+	return pool.users[index]
+}
+
+// Close releases the connections of the VUs.
+func (pool *VUPool) Close() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, vu := range pool.users {
+		vu.Client.Close()
+	}
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"goload/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrepareSeedsTheCookiesOfEveryRequestOnce(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Values("Cookie")
+		if r.URL.Path == "/rotate" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "rotated", Path: "/"})
+		}
+	}))
+	defer server.Close()
+
+	global := &Global{CookieJar: &CookieJar{SeedFromRequest: true}}
+	vu := NewVUPool(global, false).Get(0)
+	send := func(request types.HTTPRequest) {
+		t.Helper()
+		vu.beginIteration()
+		request = vu.prepare(request)
+		if len(request.Cookies) != 0 {
+			t.Fatalf("cookies %v left on the request", request.Cookies)
+		}
+		req, _ := http.NewRequest(http.MethodGet, request.URI, nil)
+		if _, err := vu.Client.ExecuteRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A phase request with its own cookies, never seen by the pool
+	phase := types.HTTPRequest{
+		URI:     server.URL + "/rotate",
+		Cookies: []types.HTTPClientCookie{{Name: "session", Value: "initial"}},
+	}
+	send(phase)
+	if len(received) != 1 || received[0] != "session=initial" {
+		t.Fatalf("first request got cookies %q, want [session=initial]", received)
+	}
+	// Sending it again must not overwrite the value set by the server
+	phase.URI = server.URL + "/other"
+	phase.Cookies = []types.HTTPClientCookie{{Name: "session", Value: "initial"}}
+	send(phase)
+	if len(received) != 1 || received[0] != "session=rotated" {
+		t.Fatalf("second request got cookies %q, want [session=rotated]", received)
+	}
+}
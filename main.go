@@ -2,11 +2,29 @@ package main
 
 import (
 	"fmt"
+	"goload/internal/report"
 	"goload/internal/runner"
+	"os"
 )
 
 func main() {
-	executor, err := runner.LoadFromYaml("config/spike.yml")
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: goload report <summary.json> <report.html>")
+			os.Exit(2)
+		}
+		if err := report.Generate(os.Args[2], os.Args[3]); err != nil {
+			fmt.Printf("Failed to generate the report: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := "config/spike.yml"
+	if len(os.Args) > 1 {
+		configPath = os.Args[1]
+	}
+	executor, err := runner.LoadFromYaml(configPath)
 	if err != nil {
 		fmt.Printf("Failed to load config: %s\n", err)
 	}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// DNSConfig controls how the client resolves the hosts it connects to.
+// Hosts overrides the resolution like a hosts file, connections being spread
+// over the addresses of a host in turn.
+type DNSConfig struct {
+	Hosts    map[string][]string `yaml:"hosts,omitempty"`     // Hostname to IP addresses
+	CacheTTL time.Duration       `yaml:"cache_ttl,omitempty"` // Keep resolved addresses for this long, no caching when unset
+	Resolver string              `yaml:"resolver,omitempty"`  // Empty for the system resolver, "go" for the Go resolver, or a DNS server address
+}
+
+func (c *DNSConfig) Validate() error {
+	for host, addresses := range c.Hosts {
+		if len(addresses) == 0 {
+			return fmt.Errorf("no address for host %s", host)
+		}
+		for _, address := range addresses {
+			if net.ParseIP(address) == nil {
+				return fmt.Errorf("invalid address %s for host %s", address, host)
+			}
+		}
+	}
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("invalid dns cache_ttl: %s", c.CacheTTL)
+	}
+	return nil
+}
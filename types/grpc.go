@@ -0,0 +1,17 @@
+package types
+
+// GRPCRequest describes the call made by a grpc request. The service
+// descriptors come from DescriptorSet, ProtoFiles or, when neither is set,
+// from the server reflection service.
+type GRPCRequest struct {
+	Target        string            `yaml:"target"`                   // host:port
+	Method        string            `yaml:"method"`                   // Full method name: package.Service/Method
+	Message       string            `yaml:"message,omitempty"`        // Request message as JSON
+	Messages      []string          `yaml:"messages,omitempty"`       // Messages sent by client and bidirectional streaming calls
+	Metadata      map[string]string `yaml:"metadata,omitempty"`       // Sent along the request headers
+	DescriptorSet string            `yaml:"descriptor_set,omitempty"` // File produced by protoc --descriptor_set_out --include_imports
+	ProtoFiles    []string          `yaml:"proto_files,omitempty"`
+	ImportPaths   []string          `yaml:"import_paths,omitempty"`
+	TLS           bool              `yaml:"tls,omitempty"`
+	SkipVerify    bool              `yaml:"skip_verify,omitempty"`
+}
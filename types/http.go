@@ -35,22 +35,214 @@ type HTTPResponse struct {
 	Body          string
 	Headers       []HTTPClientHeader `yaml:"headers,omitempty"`
 	Cookies       []HTTPClientCookie `yaml:"cookies,omitempty"`
+	FinalURL      string
+	Hops          []HopMetric
 	RequestMetric *RequestMetric
 	NetworkMetric *NetworkMetric
+	WebSocket     *WebSocketMetric
+	GRPC          *GRPCMetric
+	SSE           *SSEMetric
+	Socket        *SocketMetric
 	Error         error
 }
 
+type RequestKind string
+
+const (
+	HTTPKind      RequestKind = "http"
+	GraphQLKind   RequestKind = "graphql"
+	WebSocketKind RequestKind = "websocket"
+	GRPCKind      RequestKind = "grpc"
+	SSEKind       RequestKind = "sse"
+	TCPKind       RequestKind = "tcp"
+	UDPKind       RequestKind = "udp"
+)
+
+// HTTPRequest describes the request sent by the VUs. At most one of Body,
+// BodyFile, Form, Multipart and JSON can be set. Kind selects how the request
+// is built and its response judged, plain HTTP when empty.
 type HTTPRequest struct {
-	Method    HttpMethod         `yaml:"method"`
-	URI       string             `yaml:"uri"`
-	UserAgent UserAgent          `yaml:"user_agent"`
-	Headers   []HTTPClientHeader `yaml:"headers"`
-	Body      string             `yaml:"body"`
-	Cookies   []HTTPClientCookie `yaml:"cookies"`
+	Name           string             `yaml:"name,omitempty"`
+	Kind           RequestKind        `yaml:"kind,omitempty"`
+	Method         HttpMethod         `yaml:"method"`
+	URI            string             `yaml:"uri"`
+	UserAgent      UserAgent          `yaml:"user_agent"`
+	Headers        []HTTPClientHeader `yaml:"headers"`
+	Body           string             `yaml:"body"`
+	BodyFile       string             `yaml:"body_file,omitempty"` // Rendered as a text/template on each request
+	Form           map[string]string  `yaml:"form,omitempty"`
+	Multipart      *MultipartBody     `yaml:"multipart,omitempty"`
+	JSON           interface{}        `yaml:"json,omitempty"`
+	Compression    string             `yaml:"compression,omitempty"`     // Content-Encoding applied to the body: gzip, deflate, br or zstd
+	AcceptEncoding string             `yaml:"accept_encoding,omitempty"` // Defaults to gzip
+	GraphQL        *GraphQLRequest    `yaml:"graphql,omitempty"`
+	WebSocket      *WebSocketScenario `yaml:"websocket,omitempty"`
+	GRPC           *GRPCRequest       `yaml:"grpc,omitempty"`
+	SSE            *SSEStream         `yaml:"sse,omitempty"`
+	TCP            *TCPRequest        `yaml:"tcp,omitempty"`
+	UDP            *UDPRequest        `yaml:"udp,omitempty"`
+	Cookies        []HTTPClientCookie `yaml:"cookies"`
+	Auth           *AuthConfig        `yaml:"auth,omitempty"`
+	RateLimit      *RateLimit         `yaml:"rate_limit,omitempty"`
+	Tags           map[string]string  `yaml:"tags,omitempty"` // Added to the tags of the request metrics
+}
+
+// GraphQLRequest is the operation of a graphql request, sent as a JSON body.
+type GraphQLRequest struct {
+	Query         string                 `yaml:"query" json:"query"`
+	Variables     map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+	OperationName string                 `yaml:"operation_name,omitempty" json:"operationName,omitempty"`
+}
+
+type MultipartBody struct {
+	Fields map[string]string `yaml:"fields,omitempty"`
+	Files  []MultipartFile   `yaml:"files,omitempty"`
+}
+
+type MultipartFile struct {
+	Field       string `yaml:"field"`
+	Path        string `yaml:"path"`
+	FileName    string `yaml:"file_name,omitempty"`    // Defaults to the base name of Path
+	ContentType string `yaml:"content_type,omitempty"` // Defaults to application/octet-stream
 }
 
 func (r *HTTPRequest) Summary() string {
-	return fmt.Sprintf("Method: %s | URI: %s | UserAgent: %s | Body: %s | Headers size: %d | Cookies size: %d", r.Method, r.URI, r.UserAgent, r.Body, len(r.Headers), len(r.Cookies))
+	if r.Name != "" {
+		return fmt.Sprintf("Name: %s | %s", r.Name, r.summary())
+	}
+	return r.summary()
+}
+
+func (r *HTTPRequest) summary() string {
+	return fmt.Sprintf("Method: %s | URI: %s | UserAgent: %s | Body: %s | Headers size: %d | Cookies size: %d", r.Method, r.URI, r.UserAgent, r.bodySummary(), len(r.Headers), len(r.Cookies))
+}
+
+func (r *HTTPRequest) bodySummary() string {
+	switch {
+	case r.BodyFile != "":
+		return "file " + r.BodyFile
+	case r.Form != nil:
+		return fmt.Sprintf("form with %d fields", len(r.Form))
+	case r.Multipart != nil:
+		return fmt.Sprintf("multipart with %d fields and %d files", len(r.Multipart.Fields), len(r.Multipart.Files))
+	case r.JSON != nil:
+		return "json"
+	default:
+		return r.Body
+	}
+}
+
+type AuthType string
+
+const (
+	BasicAuth               AuthType = "basic"
+	BearerAuth              AuthType = "bearer"
+	OAuth2ClientCredentials AuthType = "oauth2_client_credentials"
+	OAuth2Password          AuthType = "oauth2_password"
+	SetupTokenAuth          AuthType = "setup"
+)
+
+// AuthConfig describes how requests are authenticated. Fields are used
+// depending on Type: Username/Password for basic auth and the password grant,
+// Token for a static bearer, TokenURL/ClientID/ClientSecret/Scopes for OAuth2
+// grants and Setup/TokenPath for a token extracted from a setup request.
+type AuthConfig struct {
+	Type          AuthType      `yaml:"type"`
+	Username      string        `yaml:"username,omitempty"`
+	Password      string        `yaml:"password,omitempty"`
+	Token         string        `yaml:"token,omitempty"`
+	TokenURL      string        `yaml:"token_url,omitempty"`
+	ClientID      string        `yaml:"client_id,omitempty"`
+	ClientSecret  string        `yaml:"client_secret,omitempty"`
+	ClientAuth    string        `yaml:"client_auth,omitempty"` // "header" (default) or "body"
+	Scopes        []string      `yaml:"scopes,omitempty"`
+	RefreshBefore time.Duration `yaml:"refresh_before,omitempty"` // Refresh margin before expiry, default 30s
+	TokenTTL      time.Duration `yaml:"token_ttl,omitempty"`      // Lifetime of tokens returned without expires_in
+	Setup         *HTTPRequest  `yaml:"setup,omitempty"`
+	TokenPath     string        `yaml:"token_path,omitempty"` // Dotted path of the token in the setup JSON response
+}
+
+type SigningType string
+
+const (
+	HMACSigning  SigningType = "hmac"
+	SigV4Signing SigningType = "aws_sigv4"
+)
+
+// SigningConfig describes how requests are signed. HMAC signatures are
+// computed over the Components of the request (method, path, query,
+// timestamp, body_sha256 or body) joined by new lines; SigV4 follows the AWS
+// Signature Version 4 process.
+type SigningConfig struct {
+	Type            SigningType `yaml:"type"`
+	Secret          string      `yaml:"secret,omitempty"`
+	KeyID           string      `yaml:"key_id,omitempty"`
+	KeyIDHeader     string      `yaml:"key_id_header,omitempty"`    // Default X-Key-Id
+	Algorithm       string      `yaml:"algorithm,omitempty"`        // sha256 (default), sha512 or sha1
+	Encoding        string      `yaml:"encoding,omitempty"`         // hex (default) or base64
+	Header          string      `yaml:"header,omitempty"`           // Default X-Signature
+	TimestampHeader string      `yaml:"timestamp_header,omitempty"` // Default X-Timestamp
+	Components      []string    `yaml:"components,omitempty"`       // Default method, path, timestamp, body_sha256
+	AccessKey       string      `yaml:"access_key,omitempty"`
+	SecretKey       string      `yaml:"secret_key,omitempty"`
+	SessionToken    string      `yaml:"session_token,omitempty"`
+	Region          string      `yaml:"region,omitempty"`
+	Service         string      `yaml:"service,omitempty"`
+}
+
+type BodyMode string
+
+const (
+	AutoBody    BodyMode = "auto"    // Keep the full body only when a check needs it, discard it otherwise
+	DiscardBody BodyMode = "discard" // Only count the body bytes
+	PrefixBody  BodyMode = "prefix"  // Keep the first MaxBytes bytes
+	FullBody    BodyMode = "full"
+)
+
+const DefaultBodyPrefixBytes = 1024
+
+// ResponseBodyConfig controls how much of the response bodies is kept in
+// memory. Bodies are always read to the end and streamed through the checks.
+type ResponseBodyConfig struct {
+	Mode     BodyMode `yaml:"mode,omitempty"`
+	MaxBytes int64    `yaml:"max_bytes,omitempty"` // Prefix size, DefaultBodyPrefixBytes when unset
+}
+
+func (c *ResponseBodyConfig) Validate() error {
+	switch c.Mode {
+	case "", AutoBody, DiscardBody, PrefixBody, FullBody:
+	default:
+		return fmt.Errorf("unknown response_body mode: %s", c.Mode)
+	}
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("invalid response_body max_bytes: %d", c.MaxBytes)
+	}
+	return nil
+}
+
+// RedirectPolicy controls how the client reacts to 3xx responses. A nil policy
+// follows up to DefaultMaxRedirectHops redirects, like net/http does.
+type RedirectPolicy struct {
+	Follow         *bool `yaml:"follow,omitempty"`
+	MaxHops        int   `yaml:"max_hops,omitempty"`
+	PreserveMethod *bool `yaml:"preserve_method,omitempty"` // Keep method and body on 307/308
+}
+
+const DefaultMaxRedirectHops = 10
+
+func (p *RedirectPolicy) FollowsRedirects() bool {
+	return p == nil || p.Follow == nil || *p.Follow
+}
+
+func (p *RedirectPolicy) MaxRedirectHops() int {
+	if p == nil || p.MaxHops <= 0 {
+		return DefaultMaxRedirectHops
+	}
+	return p.MaxHops
+}
+
+func (p *RedirectPolicy) PreservesMethod() bool {
+	return p == nil || p.PreserveMethod == nil || *p.PreserveMethod
 }
 
 type HTTPClientHeader struct {
@@ -97,6 +289,13 @@ func (requestCookie *HTTPClientCookie) convertToCookie() http.Cookie {
 	}
 }
 
+func (requestCookie *HTTPClientCookie) HTTPCookie() *http.Cookie {
+	cookie := requestCookie.convertToCookie()
+	cookie.Secure = requestCookie.Secure
+	cookie.HttpOnly = requestCookie.HTTPOnly
+	return &cookie
+}
+
 func (requestCookie *HTTPClientCookie) parse(cookie http.Cookie) HTTPClientCookie {
 	return HTTPClientCookie{
 		Name:       cookie.Name,
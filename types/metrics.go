@@ -2,17 +2,120 @@ package types
 
 import "time"
 
+// RequestMetric is the outcome of a request. Protocol is empty for HTTP
+// requests, whose status code decides of the success.
 type RequestMetric struct {
 	Duration   time.Duration
 	StatusCode int
+	Protocol   string
+	Failed     bool
+	NotSent    bool // Failed before being sent, the request has no latency
+	Timing     RequestTiming
+	Tags       map[string]string // Test, phase, segment, name, method, url, status and custom tags
+	Timestamp  time.Time         // End of the request
+	Error      string
 }
 
+// RequestTiming breaks the time of a request down, over all the hops of its
+// redirect chain. The connection phases are zero when a kept-alive
+// connection is reused. ProxyConnect covers the connection to the proxy and
+// the tunnel set up through it; FirstByte is measured on the last hop.
+type RequestTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLS          time.Duration
+	ProxyConnect time.Duration
+	FirstByte    time.Duration
+}
+
+// HasLatency tells whether Duration is a request latency. Requests never sent
+// have none, and websocket sessions are timed by their WebSocketMetric.
+func (m RequestMetric) HasLatency() bool {
+	return !m.NotSent && m.Protocol != "websocket"
+}
+
+func (m RequestMetric) Succeeded() bool {
+	if m.Failed {
+		return false
+	}
+	if m.Protocol == "" {
+		return m.StatusCode >= 200 && m.StatusCode < 300
+	}
+	return true
+}
+
+// HopMetric describes a single request/response exchange of a redirect chain.
+type HopMetric struct {
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+}
+
+// NetworkMetric holds the body sizes of an exchange. BytesRecv is the size on
+// the wire, before the response content coding is undone.
 type NetworkMetric struct {
-	BytesSent int64
-	BytesRecv int64
+	BytesSent        int64
+	BytesRecv        int64
+	BytesRecvDecoded int64
 }
 
 type CheckMetric struct {
 	Id     string
-	status bool
+	Test   string
+	Passed bool
+}
+
+// GraphQLMetric counts the errors returned by a GraphQL operation.
+type GraphQLMetric struct {
+	OperationName string
+	Errors        int
+	Failed        bool // Errors were returned, or the response was not GraphQL
+}
+
+// WebSocketMetric describes a websocket session. RoundTrips holds the time
+// between each sent message and the message matching the following expect.
+type WebSocketMetric struct {
+	ConnectTime      time.Duration
+	SessionTime      time.Duration
+	RoundTrips       []time.Duration
+	MessagesSent     int64
+	MessagesReceived int64
+	AbnormalClose    bool
+}
+
+// GRPCMetric describes a gRPC call. Code is the name of its gRPC status code.
+type GRPCMetric struct {
+	Method           string
+	Code             string
+	MessagesSent     int64
+	MessagesReceived int64
+}
+
+// SSEMetric describes an event stream. Gaps holds the time elapsed between
+// consecutive events.
+type SSEMetric struct {
+	TimeToFirstEvent time.Duration
+	StreamTime       time.Duration
+	Gaps             []time.Duration
+	Events           int64
+	InvalidEvents    int64
+}
+
+// SocketMetric describes a tcp or udp exchange. ConnectTime is only set once
+// a tcp connection is established, ResponseTime is the time between the end
+// of the send and the reply, set when a reply was awaited.
+type SocketMetric struct {
+	Protocol     string
+	ConnectTime  time.Duration
+	ResponseTime time.Duration
+	Connected    bool
+	AwaitedReply bool
+	TimedOut     bool
+}
+
+// RateLimitMetric describes the wait of a request for its rate limit.
+type RateLimitMetric struct {
+	Key       string
+	Wait      time.Duration
+	Throttled bool
 }
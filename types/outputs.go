@@ -0,0 +1,176 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteWriteConfig pushes the request metrics to a Prometheus remote write
+// endpoint at every interval, for runs too short lived to be scraped.
+type RemoteWriteConfig struct {
+	URL            string            `yaml:"url"`
+	Interval       time.Duration     `yaml:"interval,omitempty"`        // Default 10s
+	Headers        map[string]string `yaml:"headers,omitempty"`         // e.g. Authorization
+	ExternalLabels map[string]string `yaml:"external_labels,omitempty"` // Added to every series, e.g. the CI job
+	Labels         []string          `yaml:"labels,omitempty"`          // Tags turned into labels, every tag by default
+	Buckets        []float64         `yaml:"buckets,omitempty"`         // Latency histogram upper bounds in seconds
+	BatchSize      int               `yaml:"batch_size,omitempty"`      // Series per request, default 500
+	Retries        *int              `yaml:"retries,omitempty"`         // Retries of a failed request, default 3, 0 disables them
+	Timeout        time.Duration     `yaml:"timeout,omitempty"`         // Per request, default 10s
+}
+
+func (c *RemoteWriteConfig) Validate() error {
+	endpoint, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid remote write url %s: %s", c.URL, err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("remote write requires an http or https url")
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.BatchSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return fmt.Errorf("invalid remote write settings")
+	}
+	for name := range c.ExternalLabels {
+		if LabelName(name) != name || strings.HasPrefix(name, "__") || name == "le" {
+			return fmt.Errorf("invalid remote write external label: %s", name)
+		}
+		for _, label := range c.Labels {
+			if LabelName(label) == name {
+				return fmt.Errorf("remote write external label %s collides with the %s tag", name, label)
+			}
+		}
+	}
+	return nil
+}
+
+// OTLPConfig exports the request metrics, and optionally a client span per
+// request, to an OpenTelemetry collector with OTLP/HTTP JSON.
+type OTLPConfig struct {
+	Endpoint    string            `yaml:"endpoint"`               // e.g. http://localhost:4318, /v1/metrics and /v1/traces are appended
+	Headers     map[string]string `yaml:"headers,omitempty"`      // e.g. Authorization
+	ServiceName string            `yaml:"service_name,omitempty"` // Default goload
+	Interval    time.Duration     `yaml:"interval,omitempty"`     // Default 10s
+	Tags        []string          `yaml:"tags,omitempty"`         // Metric tags turned into attributes, every tag by default
+	Buckets     []float64         `yaml:"buckets,omitempty"`      // Latency histogram upper bounds in seconds
+	Traces      bool              `yaml:"traces,omitempty"`       // Create a client span per request and send its traceparent header
+	SampleRate  float64           `yaml:"sample_rate,omitempty"`  // Share of the requests traced, default 1
+	BatchSize   int               `yaml:"batch_size,omitempty"`   // Spans per request, default 512
+	BufferSize  int               `yaml:"buffer_size,omitempty"`  // Results queued before dropping, default 10000
+	Retries     *int              `yaml:"retries,omitempty"`      // Default 3, 0 disables them
+	Timeout     time.Duration     `yaml:"timeout,omitempty"`      // Per request, default 10s
+}
+
+func (c *OTLPConfig) Validate() error {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid otlp endpoint %s: %s", c.Endpoint, err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("otlp requires an http or https endpoint")
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("invalid otlp sample_rate: %g", c.SampleRate)
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.BatchSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return fmt.Errorf("invalid otlp settings")
+	}
+	return nil
+}
+
+// TraceSampleRate is the share of the requests traced, none when traces are
+// disabled.
+func (c *OTLPConfig) TraceSampleRate() float64 {
+	if !c.Traces {
+		return 0
+	}
+	if c.SampleRate == 0 {
+		return 1
+	}
+	return c.SampleRate
+}
+
+// InfluxConfig writes the request metrics in the InfluxDB line protocol.
+type InfluxConfig struct {
+	URL        string            `yaml:"url"`                   // Write endpoint, e.g. http://influx:8086/api/v2/write?org=o&bucket=b
+	Token      string            `yaml:"token,omitempty"`       // Sent as "Authorization: Token <token>"
+	Headers    map[string]string `yaml:"headers,omitempty"`     // e.g. Authorization for InfluxDB 1.x
+	Prefix     string            `yaml:"prefix,omitempty"`      // Measurement prefix, default goload
+	Mode       string            `yaml:"mode,omitempty"`        // "interval" (default) or "request"
+	Interval   time.Duration     `yaml:"interval,omitempty"`    // Default 10s
+	Tags       []string          `yaml:"tags,omitempty"`        // Metric tags kept, every tag by default
+	BatchSize  int               `yaml:"batch_size,omitempty"`  // Lines per request, default 5000
+	BufferSize int               `yaml:"buffer_size,omitempty"` // Results queued before dropping, default 10000
+	Retries    *int              `yaml:"retries,omitempty"`     // Default 3, 0 disables them
+	Timeout    time.Duration     `yaml:"timeout,omitempty"`     // Per request, default 10s
+}
+
+func (c *InfluxConfig) Validate() error {
+	endpoint, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid influx url %s: %s", c.URL, err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("influx requires an http or https url")
+	}
+	if err := validateMode(c.Mode); err != nil {
+		return err
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.BatchSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return fmt.Errorf("invalid influx settings")
+	}
+	return nil
+}
+
+// StatsDConfig sends the request metrics to a StatsD or DogStatsD agent.
+type StatsDConfig struct {
+	Address       string        `yaml:"address"`                   // e.g. 127.0.0.1:8125
+	Prefix        string        `yaml:"prefix,omitempty"`          // Metric name prefix, default "goload."
+	DogStatsD     bool          `yaml:"dogstatsd,omitempty"`       // Send the metric tags, DogStatsD style
+	Mode          string        `yaml:"mode,omitempty"`            // "interval" (default) or "request"
+	Interval      time.Duration `yaml:"interval,omitempty"`        // Default 10s
+	Tags          []string      `yaml:"tags,omitempty"`            // Metric tags kept, every tag by default
+	MaxPacketSize int           `yaml:"max_packet_size,omitempty"` // Default 1432 bytes
+	BufferSize    int           `yaml:"buffer_size,omitempty"`     // Results queued before dropping, default 10000
+}
+
+func (c *StatsDConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("statsd requires an address")
+	}
+	if err := validateMode(c.Mode); err != nil {
+		return err
+	}
+	if c.Interval < 0 || c.MaxPacketSize < 0 {
+		return fmt.Errorf("invalid statsd settings")
+	}
+	return nil
+}
+
+// LabelName replaces the characters Prometheus does not allow in label names.
+func LabelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func validateMode(mode string) error {
+	switch mode {
+	case "", "interval", "request":
+		return nil
+	}
+	return fmt.Errorf("unsupported output mode: %s", mode)
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ProxyConfig routes the traffic of the VUs through http, https or socks5
+// proxies. With a list, the VUs are spread over its proxies, each VU starting
+// with the next one; Rotate moves every VU to the next proxy on each
+// iteration.
+type ProxyConfig struct {
+	URL      string   `yaml:"url,omitempty"`  // e.g. http://proxy:3128, socks5://proxy:1080
+	List     []string `yaml:"list,omitempty"` // Used instead of URL
+	Rotate   bool     `yaml:"rotate,omitempty"`
+	Username string   `yaml:"username,omitempty"` // For the proxies without credentials in their URL
+	Password string   `yaml:"password,omitempty"`
+}
+
+// URLs parses the proxies of the config, with their credentials.
+func (p *ProxyConfig) URLs() ([]*url.URL, error) {
+	rawURLs := p.List
+	if len(rawURLs) == 0 && p.URL != "" {
+		rawURLs = []string{p.URL}
+	}
+	if len(rawURLs) == 0 {
+		return nil, fmt.Errorf("proxy requires a url or a list")
+	}
+
+	proxies := make([]*url.URL, 0, len(rawURLs))
+	for _, rawURL := range rawURLs {
+		proxyURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %s: %s", rawURL, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %s", rawURL)
+		}
+		if proxyURL.User == nil && p.Username != "" {
+			proxyURL.User = url.UserPassword(p.Username, p.Password)
+		}
+		proxies = append(proxies, proxyURL)
+	}
+	return proxies, nil
+}
@@ -0,0 +1,28 @@
+package types
+
+import "fmt"
+
+// RateLimit caps the rate of a request across all the VUs with a token
+// bucket refilled with Rate tokens per second and holding up to Burst tokens.
+// Requests limited by the same name, or host, share their bucket.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate"`            // Requests per second
+	Burst int     `yaml:"burst,omitempty"` // Defaults to 1
+	By    string  `yaml:"by,omitempty"`    // "name" (default, the host for unnamed requests) or "host"
+}
+
+// Validate rejects rate limits that cannot be applied.
+func (l *RateLimit) Validate() error {
+	if l.Rate <= 0 {
+		return fmt.Errorf("rate limit requires a positive rate, got %v", l.Rate)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("rate limit burst cannot be negative, got %d", l.Burst)
+	}
+	switch l.By {
+	case "", "name", "host":
+	default:
+		return fmt.Errorf("unknown rate limit key %q, expected name or host", l.By)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// TCPRequest describes the exchange made by a tcp request: a connection is
+// opened, Payload is sent and the reply is read until Delimiter or Length
+// is reached. With neither set, the reply is read until the server closes
+// the connection or ReadTimeout expires.
+type TCPRequest struct {
+	Address     string        `yaml:"address"` // host:port
+	Payload     string        `yaml:"payload,omitempty"`
+	Delimiter   string        `yaml:"delimiter,omitempty"` // e.g. "\r\n"
+	Length      int           `yaml:"length,omitempty"`    // Number of bytes to read
+	ReadTimeout time.Duration `yaml:"read_timeout,omitempty"`
+	TLS         bool          `yaml:"tls,omitempty"`
+	SkipVerify  bool          `yaml:"skip_verify,omitempty"`
+}
+
+// UDPRequest describes the datagram sent by a udp request and whether a
+// reply datagram is awaited.
+type UDPRequest struct {
+	Address     string        `yaml:"address"` // host:port
+	Payload     string        `yaml:"payload,omitempty"`
+	AwaitReply  bool          `yaml:"await_reply,omitempty"`
+	ReadTimeout time.Duration `yaml:"read_timeout,omitempty"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// SSEStream describes how long a sse request listens to the event stream
+// opened by the request, and how its events are validated.
+type SSEStream struct {
+	MaxEvents int           `yaml:"max_events,omitempty"` // Stop after this many events
+	Duration  time.Duration `yaml:"duration,omitempty"`   // Stop after this long
+	Event     string        `yaml:"event,omitempty"`      // Only count events of this type
+	Expect    string        `yaml:"expect,omitempty"`     // Regular expression the event data must match
+}
+
+func (s *SSEStream) Validate() error {
+	if s.Expect != "" {
+		if _, err := regexp.Compile(s.Expect); err != nil {
+			return fmt.Errorf("invalid sse expect: %s", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package types
+
+// Thresholds decide whether a test passed. The test fails when one of the
+// PassIf conditions does not hold or one of the FailIf conditions holds.
+type Thresholds struct {
+	PassIf []ThresholdCondition `yaml:"pass_if,omitempty"`
+	FailIf []ThresholdCondition `yaml:"fail_if,omitempty"`
+}
+
+// ThresholdCondition compares a metric of the test to a target such as
+// "<=200" or "<90%". Metrics: requests, rps, error_rate_pct, availability,
+// checks_pass_pct and latency_ms.<min|mean|p50|p75|p90|p95|p99|max>.
+type ThresholdCondition struct {
+	Metric string `yaml:"metric"`
+	Target string `yaml:"target"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// WebSocketScenario is the script run by a websocket request on a connection
+// opened to the request URI, with the request headers and cookies.
+type WebSocketScenario struct {
+	Subprotocols []string        `yaml:"subprotocols,omitempty"`
+	Steps        []WebSocketStep `yaml:"steps"`
+	Hold         time.Duration   `yaml:"hold,omitempty"` // Keep the connection open once the steps are done
+}
+
+// WebSocketStep sends a message, waits for a message matching Expect, or
+// both: the time between the send and the match is a round trip. Wait pauses
+// the script after the step.
+type WebSocketStep struct {
+	Send    string        `yaml:"send,omitempty"`
+	Binary  bool          `yaml:"binary,omitempty"`
+	Expect  string        `yaml:"expect,omitempty"`  // Regular expression
+	Timeout time.Duration `yaml:"timeout,omitempty"` // For Expect, 10s by default
+	Wait    time.Duration `yaml:"wait,omitempty"`
+}
+
+func (s *WebSocketScenario) Validate() error {
+	for i, step := range s.Steps {
+		if step.Expect != "" {
+			if _, err := regexp.Compile(step.Expect); err != nil {
+				return fmt.Errorf("websocket step %d: invalid expect: %s", i+1, err)
+			}
+		}
+		if step.Timeout < 0 || step.Wait < 0 {
+			return fmt.Errorf("websocket step %d: invalid timeout or wait", i+1)
+		}
+	}
+	return nil
+}